@@ -0,0 +1,177 @@
+package whiskey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+type AccessLogFormat int
+
+const (
+	AccessLogStructured AccessLogFormat = iota // One slog record per response
+	AccessLogCommon                            // NCSA Common Log Format
+	AccessLogCombined                          // NCSA Combined Log Format, the Common format followed by the referer and user agent
+)
+
+// AccessLogConfig configures the access log written after every response
+type AccessLogConfig struct {
+	Format  AccessLogFormat
+	Handler slog.Handler // Receives the records of AccessLogStructured. Defaults to a JSON handler writing to os.Stdout
+	Output  io.Writer    // Receives the lines of AccessLogCommon and AccessLogCombined. Defaults to os.Stdout
+
+	// SampleRates is the fraction of responses logged per route pattern, e.g. {"/health": 0.01} logs 1% of health checks.
+	// Routes that aren't listed are always logged and so are responses with a 5xx status
+	SampleRates map[string]float64
+	Skip        func(event ResponseEvent) bool // Responses for which Skip returns true aren't logged
+}
+
+// AccessLog writes an entry for every response once it has been sent, with the method, path, route pattern, status, bytes, duration, remote address, user agent and request ID
+func (w *Whiskey) AccessLog(config AccessLogConfig) {
+	logger := newAccessLogger(config)
+	w.OnResponse(logger.log)
+}
+
+type accessLogger struct {
+	config AccessLogConfig
+	logger *slog.Logger
+	mu     sync.Mutex // Serializes writes to Output, which may not be safe for concurrent use
+}
+
+func newAccessLogger(config AccessLogConfig) *accessLogger {
+	if config.Handler == nil {
+		config.Handler = slog.NewJSONHandler(os.Stdout, nil)
+	}
+	if config.Output == nil {
+		config.Output = os.Stdout
+	}
+
+	return &accessLogger{
+		config: config,
+		logger: slog.New(config.Handler),
+	}
+}
+
+func (l *accessLogger) log(event ResponseEvent) {
+	if !l.sampled(event) {
+		return
+	}
+
+	switch l.config.Format {
+	case AccessLogCommon, AccessLogCombined:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		fmt.Fprintln(l.config.Output, l.line(event))
+	default:
+		l.logger.LogAttrs(context.Background(), accessLogLevel(event.Status), "request", l.attrs(event)...)
+	}
+}
+
+func (l *accessLogger) sampled(event ResponseEvent) bool {
+	if l.config.Skip != nil && l.config.Skip(event) {
+		return false
+	}
+	if event.Status >= 500 {
+		return true
+	}
+
+	rate, ok := l.config.SampleRates[event.Route]
+	if !ok {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+func (l *accessLogger) attrs(event ResponseEvent) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("method", event.Method),
+		slog.String("path", event.Path),
+		slog.String("route", event.Route),
+		slog.Int("status", event.Status),
+		slog.Int("bytes", event.BodyBytes),
+		slog.Duration("duration", event.Duration),
+		slog.String("remote_addr", event.RemoteAddr),
+		slog.String("user_agent", requestHeader(event, HeaderUserAgent)),
+	}
+	if requestID := eventRequestID(event); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+	}
+	return attrs
+}
+
+// line formats the event as a Common or Combined Log Format line
+func (l *accessLogger) line(event ResponseEvent) string {
+	host := event.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	request := "-"
+	if event.Method != "" {
+		request = fmt.Sprintf("%s %s %s", event.Method, event.Path, ProtocolHTTP)
+	}
+
+	line := fmt.Sprintf("%s - - [%s] %q %d %s",
+		logField(host),
+		event.Start.Format("02/Jan/2006:15:04:05 -0700"),
+		request,
+		event.Status,
+		clfBytes(event.BodyBytes),
+	)
+
+	if l.config.Format == AccessLogCombined {
+		line += fmt.Sprintf(" %q %q", logField(requestHeader(event, HeaderReferer)), logField(requestHeader(event, HeaderUserAgent)))
+	}
+
+	return line
+}
+
+func accessLogLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func requestHeader(event ResponseEvent, key string) string {
+	value, _ := HttpRequest{headers: event.Headers}.getHeader(key)
+	return value
+}
+
+// eventRequestID returns the ID assigned to the request, or the one sent by the client if none was assigned
+func eventRequestID(event ResponseEvent) string {
+	if event.Context != nil {
+		if requestID, ok := event.Context.GetString(RequestIDKey); ok {
+			return requestID
+		}
+	}
+	return requestHeader(event, HeaderRequestID)
+}
+
+// logField returns "-" for missing values, as the NCSA formats expect
+func logField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func clfBytes(size int) string {
+	if size == 0 {
+		return "-"
+	}
+	return strconv.Itoa(size)
+}
@@ -0,0 +1,120 @@
+package whiskey
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogStructured(t *testing.T) {
+	var out bytes.Buffer
+
+	w := newTestServer()
+	w.AccessLog(AccessLogConfig{Handler: slog.NewJSONHandler(&out, nil)})
+	w.GET("/users/{id}", func(ctx Context) error {
+		ctx.Set(RequestIDKey, "req-1")
+		return ctx.String(201, "created")
+	})
+
+	serve(t, w, "GET /users/42 HTTP/1.1\r\nHost: localhost\r\nUser-Agent: curl/8.0\r\n\r\n")
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("expected a JSON record, got %q: %v", out.String(), err)
+	}
+
+	expected := map[string]any{
+		"level":      "INFO",
+		"msg":        "request",
+		"method":     "GET",
+		"path":       "/users/42",
+		"route":      "/users/{id}",
+		"status":     float64(201),
+		"bytes":      float64(len("created")),
+		"user_agent": "curl/8.0",
+		"request_id": "req-1",
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, record[key])
+		}
+	}
+	for _, key := range []string{"duration", "remote_addr"} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("expected %s in %v", key, record)
+		}
+	}
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  AccessLogFormat
+		pattern string
+	}{
+		{
+			name:    "common",
+			format:  AccessLogCommon,
+			pattern: `^\S+ - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /hello HTTP/1.1" 200 5\n$`,
+		},
+		{
+			name:    "combined",
+			format:  AccessLogCombined,
+			pattern: `^\S+ - - \[[^\]]+\] "GET /hello HTTP/1.1" 200 5 "https://example.com" "curl/8.0"\n$`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			w := newTestServer()
+			w.AccessLog(AccessLogConfig{Format: tt.format, Output: &out})
+			w.GET("/hello", func(ctx Context) error {
+				return ctx.String(200, "hello")
+			})
+
+			serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\nUser-Agent: curl/8.0\r\nReferer: https://example.com\r\n\r\n")
+
+			if !regexp.MustCompile(tt.pattern).MatchString(out.String()) {
+				t.Errorf("expected a line matching %s, got %q", tt.pattern, out.String())
+			}
+		})
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	var out bytes.Buffer
+
+	w := newTestServer()
+	w.AccessLog(AccessLogConfig{
+		Format:      AccessLogCommon,
+		Output:      &out,
+		SampleRates: map[string]float64{"/health": 0, "/broken": 0},
+		Skip: func(event ResponseEvent) bool {
+			return event.Path == "/skipped"
+		},
+	})
+	w.GET("/health", func(ctx Context) error {
+		return ctx.String(200, "ok")
+	})
+	w.GET("/broken", func(ctx Context) error {
+		return ctx.String(503, "down")
+	})
+	w.GET("/skipped", func(ctx Context) error {
+		return ctx.String(200, "ok")
+	})
+
+	serve(t, w, "GET /health HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	serve(t, w, "GET /skipped HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	serve(t, w, "GET /broken HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	serve(t, w, "GET /missing HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"GET /broken HTTP/1.1" 503`) || !strings.Contains(lines[1], `"GET /missing HTTP/1.1" 404`) {
+		t.Errorf("expected server errors and unsampled routes to be logged, got %q", out.String())
+	}
+}
@@ -0,0 +1,170 @@
+package whiskey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Causes of failed authentication. They're wrapped in the 401 HttpError the auth middlewares return, so they can be told apart with errors.Is
+var (
+	ErrMissingCredentials   = errors.New("missing credentials")
+	ErrInvalidCredentials   = errors.New("invalid credentials")
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrUnknownKey           = errors.New("unknown signing key")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrTokenExpired         = errors.New("token expired")
+	ErrTokenNotYetValid     = errors.New("token not yet valid")
+	ErrInvalidIssuer        = errors.New("invalid token issuer")
+	ErrInvalidAudience      = errors.New("invalid token audience")
+)
+
+// AuthUserKey is the DataStore key holding the name of the authenticated user, set by the BasicAuth, APIKeyAuth and JWT middlewares
+var AuthUserKey string = "auth_user"
+
+// BasicAuthConfig configures the BasicAuth middleware
+type BasicAuthConfig struct {
+	Realm string // Sent in the WWW-Authenticate header of 401 responses. Defaults to "Restricted"
+	// Users maps usernames to passwords. Passwords are compared in constant time
+	Users map[string]string
+	// Validator checks a username and password instead of Users when set. It should compare secrets with SecureCompare
+	Validator func(username string, password string) bool
+}
+
+// BasicAuth returns a middleware that authenticates requests with HTTP Basic credentials from users, which maps usernames to passwords
+func BasicAuth(users map[string]string) HttpHandler {
+	return BasicAuthWithConfig(BasicAuthConfig{Users: users})
+}
+
+// BasicAuthWithConfig returns a BasicAuth middleware with custom configuration.
+// Authenticated usernames are stored under AuthUserKey. Other requests get a 401 HttpError with a WWW-Authenticate challenge through the global error handler
+func BasicAuthWithConfig(config BasicAuthConfig) HttpHandler {
+	if config.Realm == "" {
+		config.Realm = "Restricted"
+	}
+	if config.Validator == nil {
+		config.Validator = func(username string, password string) bool {
+			expected, ok := config.Users[username]
+			// Unknown users are still compared so response times don't reveal which usernames exist
+			return SecureCompare(password, expected) && ok
+		}
+	}
+
+	challenge := fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, config.Realm)
+
+	return func(ctx Context) error {
+		username, password, ok := parseBasicAuth(ctx)
+		if !ok || !config.Validator(username, password) {
+			ctx.SetHeader(HeaderWWWAuthenticate, challenge)
+			return unauthorizedError("invalid credentials", ErrInvalidCredentials)
+		}
+
+		ctx.Set(AuthUserKey, username)
+		return nil
+	}
+}
+
+func parseBasicAuth(ctx Context) (string, string, bool) {
+	credentials, ok := authorizationCredentials(ctx, "Basic")
+	if !ok {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
+// APIKeyConfig configures the APIKeyAuth middleware
+type APIKeyConfig struct {
+	// Header the key is read from. Defaults to X-API-Key. If it's Authorization, the key is expected as a Bearer token
+	Header string
+	Query  string // Query param the key is read from when the header is missing. Keys aren't read from the query if empty
+	// Keys maps API keys to the name of their owner, stored under AuthUserKey. Keys are compared in constant time
+	Keys map[string]string
+	// Validator returns the owner of a key instead of Keys when set. It should compare secrets with SecureCompare
+	Validator func(key string) (owner string, ok bool)
+}
+
+// APIKeyAuth returns a middleware that authenticates requests by an API key in a header or query param.
+// Requests without a valid key get a 401 HttpError through the global error handler
+func APIKeyAuth(config APIKeyConfig) HttpHandler {
+	if config.Header == "" {
+		config.Header = HeaderAPIKey
+	}
+	if config.Validator == nil {
+		config.Validator = func(key string) (string, bool) {
+			// Every key is compared, so response times don't depend on which key matched
+			var owner string
+			found := false
+			for candidate, candidateOwner := range config.Keys {
+				if SecureCompare(key, candidate) {
+					owner, found = candidateOwner, true
+				}
+			}
+			return owner, found
+		}
+	}
+
+	bearer := strings.EqualFold(config.Header, HeaderAuthorization)
+
+	return func(ctx Context) error {
+		var key string
+		var ok bool
+		if bearer {
+			key, ok = authorizationCredentials(ctx, "Bearer")
+		} else {
+			key, ok = ctx.GetHeader(config.Header)
+		}
+		if !ok && config.Query != "" {
+			key, ok = ctx.GetQueryParam(config.Query)
+		}
+
+		if !ok || key == "" {
+			return unauthorizedError("missing API key", ErrMissingCredentials)
+		}
+
+		owner, valid := config.Validator(key)
+		if !valid {
+			return unauthorizedError("invalid API key", ErrInvalidCredentials)
+		}
+
+		ctx.Set(AuthUserKey, owner)
+		return nil
+	}
+}
+
+// SecureCompare reports whether two secrets are equal in constant time. Both are hashed first so the time taken doesn't reveal their lengths either
+func SecureCompare(given string, expected string) bool {
+	givenHash := sha256.Sum256([]byte(given))
+	expectedHash := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(givenHash[:], expectedHash[:]) == 1
+}
+
+// authorizationCredentials returns the credentials of the Authorization header if it uses the given scheme. Schemes are matched ignoring case
+func authorizationCredentials(ctx Context, scheme string) (string, bool) {
+	header, ok := ctx.GetHeader(HeaderAuthorization)
+	if !ok {
+		return "", false
+	}
+
+	givenScheme, credentials, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(givenScheme, scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(credentials), true
+}
+
+// unauthorizedError returns the 401 sent for failed authentication. The cause isn't sent to the client but can be matched with errors.Is, e.g. in MapError
+func unauthorizedError(message string, cause error) HttpError {
+	httpErr := NewHTTPErrorWithMessage(http.StatusUnauthorized, message, BodyTypeJSON)
+	httpErr.Err = cause
+	return httpErr
+}
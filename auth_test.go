@@ -0,0 +1,129 @@
+package whiskey
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func basicCredentials(username string, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func TestBasicAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		status        string
+	}{
+		{name: "valid credentials", authorization: basicCredentials("admin", "s3cret"), status: "200 OK"},
+		{name: "lowercase scheme", authorization: strings.Replace(basicCredentials("admin", "s3cret"), "Basic", "basic", 1), status: "200 OK"},
+		{name: "wrong password", authorization: basicCredentials("admin", "wrong"), status: "401 Unauthorized"},
+		{name: "unknown user", authorization: basicCredentials("guest", ""), status: "401 Unauthorized"},
+		{name: "not base64", authorization: "Basic %%%", status: "401 Unauthorized"},
+		{name: "missing header", status: "401 Unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestServer()
+			w.Use(BasicAuthWithConfig(BasicAuthConfig{Realm: "admin area", Users: map[string]string{"admin": "s3cret"}}))
+			w.GET("/admin", func(ctx Context) error {
+				user, _ := ctx.GetString(AuthUserKey)
+				return ctx.String(200, "hello "+user)
+			})
+
+			request := "GET /admin HTTP/1.1\r\nHost: localhost\r\n"
+			if tt.authorization != "" {
+				request += "Authorization: " + tt.authorization + "\r\n"
+			}
+			response := serve(t, w, request+"\r\n")
+
+			if !strings.HasPrefix(response, "HTTP/1.1 "+tt.status) {
+				t.Fatalf("expected %s, got %q", tt.status, response)
+			}
+			if tt.status == "200 OK" && !strings.HasSuffix(response, "hello admin") {
+				t.Errorf("expected the username on the context, got %q", response)
+			}
+			if tt.status != "200 OK" && !strings.Contains(response, `WWW-Authenticate: Basic realm="admin area", charset="UTF-8"`) {
+				t.Errorf("expected a challenge, got %q", response)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	keys := map[string]string{"key-123": "billing-service"}
+
+	tests := []struct {
+		name    string
+		config  APIKeyConfig
+		request string
+		status  string
+	}{
+		{
+			name:    "header",
+			config:  APIKeyConfig{Keys: keys},
+			request: "GET /data HTTP/1.1\r\nX-Api-Key: key-123\r\n\r\n",
+			status:  "200 OK",
+		},
+		{
+			name:    "query",
+			config:  APIKeyConfig{Keys: keys, Query: "api_key"},
+			request: "GET /data?api_key=key-123 HTTP/1.1\r\n\r\n",
+			status:  "200 OK",
+		},
+		{
+			name:    "query disabled",
+			config:  APIKeyConfig{Keys: keys},
+			request: "GET /data?api_key=key-123 HTTP/1.1\r\n\r\n",
+			status:  "401 Unauthorized",
+		},
+		{
+			name:    "bearer",
+			config:  APIKeyConfig{Keys: keys, Header: HeaderAuthorization},
+			request: "GET /data HTTP/1.1\r\nAuthorization: Bearer key-123\r\n\r\n",
+			status:  "200 OK",
+		},
+		{
+			name:    "wrong scheme",
+			config:  APIKeyConfig{Keys: keys, Header: HeaderAuthorization},
+			request: "GET /data HTTP/1.1\r\nAuthorization: Token key-123\r\n\r\n",
+			status:  "401 Unauthorized",
+		},
+		{
+			name:    "invalid key",
+			config:  APIKeyConfig{Keys: keys},
+			request: "GET /data HTTP/1.1\r\nX-Api-Key: key-124\r\n\r\n",
+			status:  "401 Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestServer()
+			w.Use(APIKeyAuth(tt.config))
+			w.GET("/data", func(ctx Context) error {
+				owner, _ := ctx.GetString(AuthUserKey)
+				return ctx.String(200, owner)
+			})
+
+			response := serve(t, w, tt.request)
+			if !strings.HasPrefix(response, "HTTP/1.1 "+tt.status) {
+				t.Fatalf("expected %s, got %q", tt.status, response)
+			}
+			if tt.status == "200 OK" && !strings.HasSuffix(response, "billing-service") {
+				t.Errorf("expected the owner on the context, got %q", response)
+			}
+		})
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	if !SecureCompare("secret", "secret") {
+		t.Error("expected equal secrets to match")
+	}
+	if SecureCompare("secret", "secret2") || SecureCompare("", "secret") {
+		t.Error("expected different secrets not to match")
+	}
+}
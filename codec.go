@@ -0,0 +1,115 @@
+package whiskey
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// BodyDecoder binds the body of the request in ctx into v. Decoders are registered on Whiskey for the media type they understand
+type BodyDecoder func(ctx Context, v any) error
+
+// JsonDecoder decodes an application/json body
+func JsonDecoder(ctx Context, v any) error {
+	return json.Unmarshal(ctx.Body(), v)
+}
+
+// XmlDecoder decodes an application/xml body
+func XmlDecoder(ctx Context, v any) error {
+	return xml.Unmarshal(ctx.Body(), v)
+}
+
+// FormDecoder binds an application/x-www-form-urlencoded body or the text fields of a multipart/form-data body
+func FormDecoder(ctx Context, v any) error {
+	values, err := ctx.Form()
+	if err != nil {
+		return err
+	}
+	return valuesToStructFrom(values, v, formSource)
+}
+
+func defaultDecoders() map[string]BodyDecoder {
+	return map[string]BodyDecoder{
+		MimeTypeJSON:           JsonDecoder,
+		MimeTypeXML:            XmlDecoder,
+		MimeTypeXMLText:        XmlDecoder,
+		MimeTypeFormUrlEncoded: FormDecoder,
+		MimeTypeMultipartForm:  FormDecoder,
+	}
+}
+
+// findDecoder returns the decoder registered for mediaType. Structured syntax suffixes like application/vnd.api+json fall back to the JSON and XML decoders
+func findDecoder(decoders map[string]BodyDecoder, mediaType string) (BodyDecoder, bool) {
+	if decoder, ok := decoders[mediaType]; ok {
+		return decoder, true
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		decoder, ok := decoders[MimeTypeJSON]
+		return decoder, ok
+	case strings.HasSuffix(mediaType, "+xml"):
+		decoder, ok := decoders[MimeTypeXML]
+		return decoder, ok
+	}
+
+	return nil, false
+}
+
+func unsupportedMediaTypeError(mediaType string) HttpError {
+	return NewHTTPErrorWithMessage(http.StatusUnsupportedMediaType, "unsupported Content-Type "+mediaType, BodyTypeJSON)
+}
+
+// BodyEncoder encodes data into the body of a response. Encoders are registered on Whiskey for the media type they produce and picked by Negotiate
+type BodyEncoder func(data any) ([]byte, error)
+
+// JsonEncoder encodes data as application/json
+func JsonEncoder(data any) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// XmlEncoder encodes data as application/xml
+func XmlEncoder(data any) ([]byte, error) {
+	return xml.Marshal(data)
+}
+
+// TextEncoder encodes data as text/plain. Strings and byte slices are sent as is, anything else is formatted with fmt
+func TextEncoder(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return []byte(fmt.Sprint(v)), nil
+	}
+}
+
+// HtmlTemplateEncoder returns an encoder that renders data with the named template. Register it for text/html to make HTML available to Negotiate
+func HtmlTemplateEncoder(tmpl *template.Template, name string) BodyEncoder {
+	return func(data any) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// registeredEncoder pairs an encoder with the media type it produces. Encoders are kept in registration order which is used to break ties during negotiation
+type registeredEncoder struct {
+	mediaType string
+	encoder   BodyEncoder
+}
+
+func defaultEncoders() []registeredEncoder {
+	return []registeredEncoder{
+		{mediaType: MimeTypeJSON, encoder: JsonEncoder},
+		{mediaType: MimeTypeXML, encoder: XmlEncoder},
+		{mediaType: MimeTypeText, encoder: TextEncoder},
+	}
+}
@@ -2,21 +2,54 @@ package whiskey
 
 var (
 	MimeTypeJSON           string = "application/json"
+	MimeTypeProblemJSON    string = "application/problem+json"
 	MimeTypeHTML           string = "text/html"
 	MimeTypeText           string = "text/plain"
 	MimeTypeXML            string = "application/xml"
+	MimeTypeXMLText        string = "text/xml"
 	MimeTypeFormUrlEncoded string = "application/x-www-form-urlencoded"
+	MimeTypeMultipartForm  string = "multipart/form-data"
+	MimeTypeOpenMetrics    string = "application/openmetrics-text"
 	MimeTypeJPEG           string = "image/jpeg"
 	MimeTypePNG            string = "image/png"
 )
 
 var (
-	HeaderContentType   string = "Content-Type"
-	HeaderContentLength string = "Content-Length"
-	HeaderAccept        string = "Accept"
-	HeaderConnection    string = "Connection"
+	HeaderContentType     string = "Content-Type"
+	HeaderContentLength   string = "Content-Length"
+	HeaderAccept          string = "Accept"
+	HeaderConnection      string = "Connection"
+	HeaderVary            string = "Vary"
+	HeaderAllow           string = "Allow"
+	HeaderOrigin          string = "Origin"
+	HeaderUserAgent       string = "User-Agent"
+	HeaderReferer         string = "Referer"
+	HeaderRequestID       string = "X-Request-ID"
+	HeaderAuthorization   string = "Authorization"
+	HeaderAPIKey          string = "X-API-Key"
+	HeaderWWWAuthenticate string = "WWW-Authenticate"
+	HeaderTraceParent     string = "traceparent"
+	HeaderTraceState      string = "tracestate"
+
+	HeaderAccessControlAllowOrigin      string = "Access-Control-Allow-Origin"
+	HeaderAccessControlAllowMethods     string = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowHeaders     string = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowCredentials string = "Access-Control-Allow-Credentials"
+	HeaderAccessControlExposeHeaders    string = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge           string = "Access-Control-Max-Age"
+	HeaderAccessControlRequestMethod    string = "Access-Control-Request-Method"
+	HeaderAccessControlRequestHeaders   string = "Access-Control-Request-Headers"
+
+	HeaderRetryAfter         string = "Retry-After"
+	HeaderRateLimitLimit     string = "RateLimit-Limit"
+	HeaderRateLimitRemaining string = "RateLimit-Remaining"
+	HeaderRateLimitReset     string = "RateLimit-Reset"
+	HeaderRateLimitPolicy    string = "RateLimit-Policy"
 )
 
+// RequestIDKey is the DataStore key holding the ID of the request, if one was assigned
+var RequestIDKey string = "request_id"
+
 var (
 	ProtocolHTTP  string = "HTTP/1.1"
 	ProtocolHTTPS string = "HTTPS/1.1"
@@ -1,34 +1,75 @@
 package whiskey
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"maps"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"slices"
 )
 
 // Context is an interface that represents the context of a single request. It contains all information regarding that request and is propagated through all middlewares
 type Context interface {
-	BindBody(body any) error     // The body will be a struct and the function will add the body parameters to the struct. The request body is expected to be a valid JSON
-	BindQuery(query any) error   // The query will be a struct and the function will add the query parameters to the struct.
+	// Fields are named by `query`, `path`, `header` and `form` tags for the matching Bind method and fall back to `json` tags. Header names are matched ignoring case.
+	// Fields missing from the request get the value of their `default` tag. Body fields get it when they are still zero after decoding.
+	// Bound structs are validated with their `validate` tags. Failures return an HttpError with 422 listing every failed field
+	BindBody(body any) error     // The body will be a struct and the function will add the body parameters to the struct. The body is decoded with the decoder registered for the request Content-Type, JSON if none is sent. Unsupported types return an HttpError with 415
+	BindQuery(query any) error   // The query will be a struct and the function will add the query parameters to the struct. Slice fields receive every value of a repeated key, nested structs are filled from `key[field]` params and bool fields of bare flags like `?debug` are set to true
 	BindPath(path any) error     // The path will be a struct and the function will add the path parameters to the struct.
 	BindHeader(header any) error // The header will be a struct and the function will add the header parameters to the struct.
+	BindForm(form any) error     // The form will be a struct and the function will add the fields of an application/x-www-form-urlencoded or the text fields of a multipart/form-data body to the struct.
+	Bind(req any) error          // The req will be a struct filled from the body first and then from every field tagged `path`, `query` or `header`, so those take precedence. Defaults only fill fields no source has set. It's validated once and all conversion failures, a malformed body included, are returned together in an HttpError with 400
 
 	Json(statusCode int, data any) error                         // The function will convert the data to JSON and send it as a response
 	String(statusCode int, data string) error                    // The function will send the data as a string response
 	Html(statusCode int, data string) error                      // The function will send the data as a HTML response
 	Bytes(statusCode int, contentType string, data []byte) error // The function will send the data as a byte array response. Since we won't know what content type to set, you need to pass in the appropriate type, If content type is empty, Content-Type header won't be sent
+	Negotiate(statusCode int, data any) error                    // The function will encode the data with the registered encoder that best matches the Accept header. An HttpError with 406 is returned if none is acceptable
 
-	GetQueryParam(key string) (string, bool) // The function will return the query parameter value for the given key. The boolean denotes whether the query param exists
-	GetPathParam(key string) (string, bool)  // The function will return the path parameter value for the given key. The boolean denotes whether the path param exists
-	GetHeader(key string) (string, bool)     // The function will return the header value for the given key. The boolean denotes whether the header exists
+	GetQueryParam(key string) (string, bool)         // The function will return the query parameter value for the given key. If the key is repeated, the first value is returned. The boolean denotes whether the query param exists
+	GetQueryParamValues(key string) ([]string, bool) // The function will return every value sent for the given query key in order. The boolean denotes whether the query param exists
+	GetPathParam(key string) (string, bool)          // The function will return the path parameter value for the given key. The boolean denotes whether the path param exists
+	GetHeader(key string) (string, bool)             // The function will return the header value for the given key, ignoring its case. The boolean denotes whether the header exists
 
 	GetQueryParams() map[string]string // The function will return all the query parameters
 	GetPathParams() map[string]string  // The function will return all the path parameters
 	GetHeaders() map[string]string     // The function will return all the headers
 
+	FormValue(key string) (string, bool) // The function will return the first value of the given key in a form body. The boolean denotes whether the field exists
+	Form() (map[string][]string, error)  // The function will return every field of an application/x-www-form-urlencoded body or every text field of a multipart/form-data body. An HttpError with 415 is returned for any other content type
+
+	MultipartReader() (*multipart.Reader, error) // The function will return a reader to stream the parts of a multipart/form-data body one by one from the connection. Use it instead of MultipartForm/FormFile, not alongside them
+	MultipartForm() (*MultipartForm, error)      // The function will parse a multipart/form-data body once per request. Temporary files created for large uploads are removed after the request
+	FormFile(name string) (*FormFile, error)     // The function will return the first file uploaded for the given field of a multipart/form-data body
+
 	SetHeader(key string, value string) // The function will set the header for the response.
 
-	URL() string    // The function will return the current path for which the request is being processed.
-	Method() string // The function will return the current HTTP method for the request
+	// The following methods let middlewares inspect the response after calling Next, e.g. for logging, metrics, caching or ETags
+	Status() int                        // The function will return the status code of the response, 200 if none was set
+	ResponseHeaders() map[string]string // The function will return the headers of the response. Changes to the map are sent with the response
+	ResponseSize() int                  // The function will return the size of the response body in bytes
+	Response() *HttpResponse            // The function will return the response handlers write to
+	SetResponse(response *HttpResponse) // The function will replace the response handlers write to. Middlewares can swap in NewHttpResponse() before Next and rewrite what was captured into the original afterwards
+
+	Context() context.Context      // The function will return the context of the request. It is canceled when the client disconnects, the read timeout passes or the server shuts down
+	WithContext(c context.Context) // The function will replace the context of the request, so values and deadlines attached by a middleware are seen by every handler after it
+
+	Body() []byte       // The function will return the raw request body. It's empty for multipart/form-data bodies, which are streamed through MultipartReader and MultipartForm instead
+	URL() string        // The function will return the current path for which the request is being processed.
+	Method() string     // The function will return the current HTTP method for the request
+	Route() string      // The function will return the pattern of the matched route, e.g. /users/{id}. It's empty for requests handled by the GlobalRequestHandler
+	RemoteAddr() string // The function will return the network address of the client
+	Span() *Span        // The function will return the server span of the request, nil if tracing isn't enabled
+
+	// Handlers run in the order they are registered and the chain stops as soon as one of them returns an error.
+	// Middlewares can also wrap the rest of the chain by calling Next, to run code after the handler like timing or response rewriting
+	Next() error     // The function will run the remaining handlers and return the first error. They don't run again once the calling middleware returns, so return this error unless it was handled
+	Abort()          // The function will stop the handlers after the current one from running. The response written so far is sent
+	IsAborted() bool // The function will return whether Abort was called
 
 	// The following methods are to store arbitrary key/value pairs for the duration of the request
 	Set(key string, value any)
@@ -42,28 +83,324 @@ type Context interface {
 }
 
 type RequestContext struct {
-	*DataStore // This is used as temporary storage for the request. It is not persisted across requests, but persisted across middlewares in a single request
-	request    HttpRequest
-	response   *HttpResponse
+	*DataStore    // This is used as temporary storage for the request. It is not persisted across requests, but persisted across middlewares in a single request
+	request       HttpRequest
+	response      *HttpResponse
+	engine        *Whiskey // The engine serving the request. It is nil for contexts created outside the server, in which case defaults are used
+	route         string   // Pattern of the matched route
+	remoteAddr    string
+	span          *Span // Server span of the request, set when tracing is enabled
+	ctx           context.Context
+	multipartForm *MultipartForm // Cached result of MultipartForm
+	handlers      []HttpHandler  // Middlewares and handlers of the matched route
+	index         int            // Position of the next handler to run
+	aborted       bool
+}
+
+func (r *RequestContext) Next() error {
+	for r.index < len(r.handlers) && !r.aborted {
+		handler := r.handlers[r.index]
+		r.index++
+		if err := handler(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RequestContext) Abort() {
+	r.aborted = true
+}
+
+func (r *RequestContext) IsAborted() bool {
+	return r.aborted
+}
+
+// fork returns a copy of the context to run the rest of the chain on another goroutine.
+// The copy has its own response and data store so that it never races with the original, join brings its results back
+func (r *RequestContext) fork(ctx context.Context) *RequestContext {
+	forked := *r
+	forked.ctx = ctx
+	forked.response = r.response.clone()
+	forked.DataStore = r.DataStore.clone()
+	return &forked
 }
 
-func (r RequestContext) BindBody(body any) error {
-	return json.Unmarshal(r.request.body, body)
+// join copies the results of a forked context that has finished running back into r
+func (r *RequestContext) join(forked *RequestContext) {
+	*r.response = *forked.response
+	r.DataStore = forked.DataStore
+	r.multipartForm = forked.multipartForm
+	r.index = forked.index
+	r.aborted = forked.aborted
 }
 
-func (r RequestContext) BindQuery(query any) error {
-	return mapToStruct(r.request.queryParams, query)
+// config returns the configuration of the engine serving this request
+func (r *RequestContext) config() ServerConfig {
+	if r.engine == nil {
+		return defaultConfig
+	}
+	return r.engine.config
 }
 
-func (r RequestContext) BindPath(path any) error {
-	return mapToStruct(r.request.pathParams, path)
+// release frees resources held for the request once the response is written
+func (r *RequestContext) release() error {
+	if r.multipartForm == nil {
+		return nil
+	}
+	return r.multipartForm.RemoveAll()
 }
 
-func (r RequestContext) BindHeader(header any) error {
-	return mapToStruct(r.request.headers, header)
+func (r *RequestContext) BindBody(body any) error {
+	if err := r.decodeBody(body); err != nil {
+		return err
+	}
+	if err := applyBodyDefaults(body); err != nil {
+		return bindHTTPError(err)
+	}
+	return r.validate(body)
 }
 
-func (r RequestContext) Json(statusCode int, data any) error {
+// decodeBody decodes the body with the decoder registered for the request Content-Type, JSON if none is sent.
+// Decoding failures other than HttpErrors are caused by a malformed body and returned as a 400 HttpError
+func (r *RequestContext) decodeBody(body any) error {
+	mediaType := MimeTypeJSON
+	if _, ok := r.request.getHeader(HeaderContentType); ok {
+		mediaType = r.request.mediaType()
+	}
+
+	decoder, ok := findDecoder(r.decoders(), mediaType)
+	if !ok {
+		return unsupportedMediaTypeError(mediaType)
+	}
+
+	err := decoder(r, body)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(HttpError); ok {
+		return err
+	}
+	if bindErrs, ok := err.(BindErrors); ok {
+		return bindErrs.httpError()
+	}
+
+	httpErr := newHTTPErrorWithFields(http.StatusBadRequest, "invalid request body", Json{"detail": err.Error()})
+	httpErr.Err = err
+	return httpErr
+}
+
+func (r *RequestContext) BindQuery(query any) error {
+	if err := valuesToStructFrom(r.request.query, query, querySource); err != nil {
+		return bindHTTPError(err)
+	}
+	return r.validate(query)
+}
+
+func (r *RequestContext) BindPath(path any) error {
+	if err := mapToStructFrom(r.request.pathParams, path, pathSource); err != nil {
+		return bindHTTPError(err)
+	}
+	return r.validate(path)
+}
+
+func (r *RequestContext) BindHeader(header any) error {
+	if err := mapToStructFrom(r.request.headers, header, headerSource); err != nil {
+		return bindHTTPError(err)
+	}
+	return r.validate(header)
+}
+
+func (r *RequestContext) BindForm(form any) error {
+	values, err := r.Form()
+	if err != nil {
+		return err
+	}
+	if err := valuesToStructFrom(values, form, formSource); err != nil {
+		return bindHTTPError(err)
+	}
+	return r.validate(form)
+}
+
+func (r *RequestContext) Bind(req any) error {
+	var bodyErr error
+	if r.request.hasBody() {
+		bodyErr = r.decodeBody(req)
+		// Only a malformed body is reported along with the other sources, anything else like an unsupported Content-Type fails right away
+		if httpErr, ok := bodyErr.(HttpError); bodyErr != nil && (!ok || httpErr.StatusCode != http.StatusBadRequest) {
+			return bodyErr
+		}
+	}
+
+	sources := []struct {
+		values map[string][]string
+		source bindSource
+	}{
+		{values: singleValues(r.request.pathParams), source: pathSource.only()},
+		{values: r.request.query, source: querySource.only()},
+		{values: singleValues(r.request.headers), source: headerSource.only()},
+	}
+
+	var errs BindErrors
+	bind := func(values map[string][]string, source bindSource) error {
+		err := valuesToStructFrom(values, req, source)
+		if bindErrs, ok := err.(BindErrors); ok {
+			errs = append(errs, bindErrs...)
+			return nil
+		}
+		return err
+	}
+
+	for _, s := range sources {
+		if err := bind(s.values, s.source.withDefaults(defaultsNever)); err != nil {
+			return err
+		}
+	}
+	// Defaults only fill the fields that neither the body nor any other source has set
+	for _, s := range sources {
+		if err := bind(nil, s.source.withDefaults(defaultsIfZero)); err != nil {
+			return err
+		}
+	}
+	for _, source := range bodySources {
+		if err := bind(nil, source.withDefaults(defaultsIfZero)); err != nil {
+			return err
+		}
+	}
+
+	if bodyErr != nil {
+		return bodyBindError(bodyErr.(HttpError), errs)
+	}
+	if len(errs) > 0 {
+		return errs.httpError()
+	}
+	return r.validate(req)
+}
+
+// bodyBindError reports a malformed body together with the values of the other sources that failed to bind
+func bodyBindError(bodyErr HttpError, errs BindErrors) error {
+	var bodyBindErrs BindErrors
+	if errors.As(bodyErr.Err, &bodyBindErrs) {
+		return append(bodyBindErrs, errs...).httpError()
+	}
+	if len(errs) == 0 {
+		return bodyErr
+	}
+
+	bindErr := errs.httpError()
+	fields := maps.Clone(bindErr.fields)
+	maps.Copy(fields, bodyErr.fields)
+
+	httpErr := newHTTPErrorWithFields(http.StatusBadRequest, bindErr.Message, fields)
+	httpErr.Err = errors.Join(bodyErr.Err, errs)
+	return httpErr
+}
+
+// bindHTTPError turns conversion failures into a 400 HttpError listing every field, since they're caused by the client.
+// Other errors, like passing something other than a pointer to a struct, are programming errors and returned as is
+func bindHTTPError(err error) error {
+	if bindErrs, ok := err.(BindErrors); ok {
+		return bindErrs.httpError()
+	}
+	return err
+}
+
+// validate runs the `validate` struct tag rules on a bound struct
+func (r *RequestContext) validate(v any) error {
+	validators := defaultValidators()
+	if r.engine != nil {
+		validators = r.engine.validators
+	}
+	return validateStruct(v, validators)
+}
+
+// decoders returns the body decoders registered on the engine serving this request
+func (r *RequestContext) decoders() map[string]BodyDecoder {
+	if r.engine == nil {
+		return defaultDecoders()
+	}
+	return r.engine.decoders
+}
+
+func (r *RequestContext) Form() (map[string][]string, error) {
+	switch r.request.mediaType() {
+	case MimeTypeFormUrlEncoded:
+		return parseQuery(string(r.request.body)), nil
+	case MimeTypeMultipartForm:
+		form, err := r.MultipartForm()
+		if err != nil {
+			return nil, err
+		}
+		return form.Value, nil
+	default:
+		return nil, NewHTTPErrorWithMessage(http.StatusUnsupportedMediaType, "expected Content-Type "+MimeTypeFormUrlEncoded+" or "+MimeTypeMultipartForm, BodyTypeJSON)
+	}
+}
+
+func (r *RequestContext) FormValue(key string) (string, bool) {
+	values, err := r.Form()
+	if err != nil {
+		return "", false
+	}
+	if value, ok := values[key]; ok && len(value) > 0 {
+		return value[0], true
+	}
+	return "", false
+}
+
+func (r *RequestContext) MultipartReader() (*multipart.Reader, error) {
+	contentType, _ := r.request.getHeader(HeaderContentType)
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != MimeTypeMultipartForm {
+		return nil, NewHTTPErrorWithMessage(http.StatusUnsupportedMediaType, "expected Content-Type "+MimeTypeMultipartForm, BodyTypeJSON)
+	}
+
+	boundary, ok := params["boundary"]
+	if !ok || boundary == "" {
+		return nil, NewHTTPErrorWithMessage(http.StatusBadRequest, "missing multipart boundary", BodyTypeJSON)
+	}
+
+	body := r.request.bodyReader
+	if body == nil {
+		body = bytes.NewReader(r.request.body)
+	}
+	return multipart.NewReader(body, boundary), nil
+}
+
+func (r *RequestContext) MultipartForm() (*MultipartForm, error) {
+	if r.multipartForm != nil {
+		return r.multipartForm, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	config := r.config()
+	form, err := readMultipartForm(reader, config.MaxMultipartMemory, config.MaxMultipartParts)
+	if err != nil {
+		return nil, err
+	}
+
+	r.multipartForm = form
+	return form, nil
+}
+
+func (r *RequestContext) FormFile(name string) (*FormFile, error) {
+	form, err := r.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+
+	files, ok := form.File[name]
+	if !ok || len(files) == 0 {
+		return nil, NewHTTPErrorWithMessage(http.StatusBadRequest, "missing file "+name, BodyTypeJSON)
+	}
+	return files[0], nil
+}
+
+func (r *RequestContext) Json(statusCode int, data any) error {
 	r.response.SetHeader(HeaderContentType, MimeTypeJSON)
 	if statusCode == 0 {
 		statusCode = http.StatusOK
@@ -78,7 +415,7 @@ func (r RequestContext) Json(statusCode int, data any) error {
 	return nil
 }
 
-func (r RequestContext) String(statusCode int, data string) error {
+func (r *RequestContext) String(statusCode int, data string) error {
 	r.response.SetHeader(HeaderContentType, MimeTypeText)
 	if statusCode == 0 {
 		statusCode = http.StatusOK
@@ -90,7 +427,7 @@ func (r RequestContext) String(statusCode int, data string) error {
 	return nil
 }
 
-func (r RequestContext) Html(statusCode int, data string) error {
+func (r *RequestContext) Html(statusCode int, data string) error {
 	r.response.SetHeader(HeaderContentType, MimeTypeHTML)
 	if statusCode == 0 {
 		statusCode = http.StatusOK
@@ -102,40 +439,77 @@ func (r RequestContext) Html(statusCode int, data string) error {
 	return nil
 }
 
-func (r RequestContext) GetQueryParam(key string) (string, bool) {
+func (r *RequestContext) Negotiate(statusCode int, data any) error {
+	// The response depends on Accept even when negotiation fails, so caches must know about it
+	r.response.AddHeaderValue(HeaderVary, HeaderAccept)
+
+	encoders := r.encoders()
+	offers := make([]string, len(encoders))
+	for idx, registered := range encoders {
+		offers[idx] = registered.mediaType
+	}
+
+	accept, _ := r.request.getHeader(HeaderAccept)
+	mediaType, ok := negotiate(accept, offers)
+	if !ok {
+		return NewHttpError(http.StatusNotAcceptable, BodyTypeJSON)
+	}
+
+	encoder := encoders[slices.Index(offers, mediaType)].encoder
+	b, err := encoder(data)
+	if err != nil {
+		return err
+	}
+
+	return r.Bytes(statusCode, mediaType, b)
+}
+
+// encoders returns the response encoders registered on the engine serving this request
+func (r *RequestContext) encoders() []registeredEncoder {
+	if r.engine == nil {
+		return defaultEncoders()
+	}
+	return r.engine.encoders
+}
+
+func (r *RequestContext) GetQueryParam(key string) (string, bool) {
 	if value, ok := r.request.queryParams[key]; ok {
 		return value, true
 	}
 	return "", false
 }
 
-func (r RequestContext) GetPathParam(key string) (string, bool) {
-	if value, ok := r.request.pathParams[key]; ok {
-		return value, true
+func (r *RequestContext) GetQueryParamValues(key string) ([]string, bool) {
+	if values, ok := r.request.query[key]; ok {
+		return values, true
 	}
-	return "", false
+	return nil, false
 }
 
-func (r RequestContext) GetHeader(key string) (string, bool) {
-	if value, ok := r.request.headers[key]; ok {
+func (r *RequestContext) GetPathParam(key string) (string, bool) {
+	if value, ok := r.request.pathParams[key]; ok {
 		return value, true
 	}
 	return "", false
 }
 
-func (r RequestContext) GetQueryParams() map[string]string {
+func (r *RequestContext) GetHeader(key string) (string, bool) {
+	return r.request.getHeader(key)
+}
+
+func (r *RequestContext) GetQueryParams() map[string]string {
 	return r.request.queryParams
 }
 
-func (r RequestContext) GetPathParams() map[string]string {
+func (r *RequestContext) GetPathParams() map[string]string {
 	return r.request.pathParams
 }
 
-func (r RequestContext) GetHeaders() map[string]string {
+func (r *RequestContext) GetHeaders() map[string]string {
 	return r.request.headers
 }
 
-func (r RequestContext) Bytes(statusCode int, contentType string, data []byte) error {
+func (r *RequestContext) Bytes(statusCode int, contentType string, data []byte) error {
 	r.response.SetHeader(HeaderContentType, contentType)
 	if statusCode == 0 {
 		statusCode = http.StatusOK
@@ -146,14 +520,67 @@ func (r RequestContext) Bytes(statusCode int, contentType string, data []byte) e
 	return nil
 }
 
-func (r RequestContext) SetHeader(key string, value string) {
+func (r *RequestContext) SetHeader(key string, value string) {
 	r.response.SetHeader(key, value)
 }
 
-func (r RequestContext) URL() string {
+func (r *RequestContext) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+func (r *RequestContext) WithContext(c context.Context) {
+	if c == nil {
+		panic("nil context")
+	}
+	r.ctx = c
+}
+
+func (r *RequestContext) Body() []byte {
+	return r.request.body
+}
+
+func (r *RequestContext) Status() int {
+	return r.response.StatusCode()
+}
+
+func (r *RequestContext) ResponseHeaders() map[string]string {
+	return r.response.Headers()
+}
+
+func (r *RequestContext) ResponseSize() int {
+	return len(r.response.body)
+}
+
+func (r *RequestContext) Response() *HttpResponse {
+	return r.response
+}
+
+func (r *RequestContext) SetResponse(response *HttpResponse) {
+	if response == nil {
+		panic("nil response")
+	}
+	r.response = response
+}
+
+func (r *RequestContext) URL() string {
 	return r.request.path
 }
 
-func (r RequestContext) Method() string {
+func (r *RequestContext) Method() string {
 	return r.request.method
 }
+
+func (r *RequestContext) Route() string {
+	return r.route
+}
+
+func (r *RequestContext) RemoteAddr() string {
+	return r.remoteAddr
+}
+
+func (r *RequestContext) Span() *Span {
+	return r.span
+}
@@ -3,7 +3,10 @@ package whiskey
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -11,6 +14,7 @@ func TestContextBindBody(t *testing.T) {
 	type BodyType struct {
 		Key1 string `json:"key1"`
 		Key2 string `json:"key2"`
+		Key3 string `json:"key3" default:"fallback"`
 	}
 
 	testCases := []struct {
@@ -22,13 +26,19 @@ func TestContextBindBody(t *testing.T) {
 		{
 			name:        "Valid JSON with all fields",
 			body:        []byte(`{"key1": "value1", "key2": "value2"}`),
-			expected:    BodyType{Key1: "value1", Key2: "value2"},
+			expected:    BodyType{Key1: "value1", Key2: "value2", Key3: "fallback"},
 			expectedErr: "",
 		},
 		{
 			name:        "Valid JSON with missing fields",
 			body:        []byte(`{"key1": "value1"}`),
-			expected:    BodyType{Key1: "value1", Key2: ""},
+			expected:    BodyType{Key1: "value1", Key2: "", Key3: "fallback"},
+			expectedErr: "",
+		},
+		{
+			name:        "Default is not applied to a sent field",
+			body:        []byte(`{"key3": "sent"}`),
+			expected:    BodyType{Key3: "sent"},
 			expectedErr: "",
 		},
 		{
@@ -53,7 +63,7 @@ func TestContextBindBody(t *testing.T) {
 			if tc.expectedErr == "" && err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
-			if tc.expectedErr != "" && (err == nil || err.Error() != tc.expectedErr) {
+			if tc.expectedErr != "" && (err == nil || bindErrorMessage(err) != tc.expectedErr) {
 				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
 			}
 
@@ -109,30 +119,35 @@ func TestContextBindQuery(t *testing.T) {
 				"intKey": "invalid",
 			},
 			expected:    QueryType{},
-			expectedErr: "invalid value type",
+			expectedErr: `invalid value "invalid" for field "intKey": expected int`,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
+			query := make(map[string][]string)
+			for key, value := range tc.queryParams {
+				query[key] = []string{value}
+			}
 			req := HttpRequest{
 				queryParams: tc.queryParams,
+				query:       query,
 			}
 
 			context := RequestContext{
 				request: req,
 			}
-			var query QueryType
-			err := context.BindQuery(&query)
+			var queryType QueryType
+			err := context.BindQuery(&queryType)
 			if tc.expectedErr == "" && err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
-			if tc.expectedErr != "" && (err == nil || err.Error() != tc.expectedErr) {
+			if tc.expectedErr != "" && (err == nil || bindErrorMessage(err) != tc.expectedErr) {
 				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
 			}
 
-			if query != tc.expected {
-				t.Fatalf("expected query %v, got %v", tc.expected, query)
+			if queryType != tc.expected {
+				t.Fatalf("expected query %v, got %v", tc.expected, queryType)
 			}
 		})
 	}
@@ -192,7 +207,7 @@ func TestContextBindPath(t *testing.T) {
 				"intKey": "invalid",
 			},
 			expected:    PathType{},
-			expectedErr: "invalid value type",
+			expectedErr: `invalid value "invalid" for field "intKey": expected int`,
 		},
 	}
 
@@ -210,7 +225,7 @@ func TestContextBindPath(t *testing.T) {
 			if tc.expectedErr == "" && err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
-			if tc.expectedErr != "" && (err == nil || err.Error() != tc.expectedErr) {
+			if tc.expectedErr != "" && (err == nil || bindErrorMessage(err) != tc.expectedErr) {
 				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
 			}
 
@@ -275,7 +290,7 @@ func TestContextBindHeader(t *testing.T) {
 				"intKey": "invalid",
 			},
 			expected:    HeaderType{},
-			expectedErr: "invalid value type",
+			expectedErr: `invalid value "invalid" for field "intKey": expected int`,
 		},
 	}
 
@@ -293,7 +308,7 @@ func TestContextBindHeader(t *testing.T) {
 			if tc.expectedErr == "" && err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
-			if tc.expectedErr != "" && (err == nil || err.Error() != tc.expectedErr) {
+			if tc.expectedErr != "" && (err == nil || bindErrorMessage(err) != tc.expectedErr) {
 				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
 			}
 
@@ -746,3 +761,479 @@ func TestGetHeaders(t *testing.T) {
 		t.Fatalf("expected headers map with key1=value1 and key2=value2, got %v", headers)
 	}
 }
+
+func TestContextBindQueryValues(t *testing.T) {
+	type Filter struct {
+		Name   string `json:"name"`
+		MinAge int    `json:"minAge"`
+	}
+	type QueryType struct {
+		Tags   []string `json:"tag"`
+		Ids    []int    `json:"ids"`
+		Debug  bool     `json:"debug"`
+		Filter Filter   `json:"filter"`
+	}
+
+	ctx := RequestContext{
+		request: HttpRequest{
+			query: parseQuery("tag=a&tag=b+c&ids[]=1&ids[]=2&filter[name]=john&filter[minAge]=18&debug=true"),
+		},
+	}
+
+	var query QueryType
+	if err := ctx.BindQuery(&query); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := QueryType{
+		Tags:   []string{"a", "b c"},
+		Ids:    []int{1, 2},
+		Debug:  true,
+		Filter: Filter{Name: "john", MinAge: 18},
+	}
+	if !reflect.DeepEqual(query, expected) {
+		t.Fatalf("expected query %+v, got %+v", expected, query)
+	}
+
+	values, ok := ctx.GetQueryParamValues("tag")
+	if !ok || !reflect.DeepEqual(values, []string{"a", "b c"}) {
+		t.Fatalf("expected tag values [a b c], got %v", values)
+	}
+
+	type Flags struct {
+		Debug   bool  `query:"debug"`
+		Verbose *bool `query:"verbose"`
+		Dry     bool  `query:"dry"`
+	}
+	var flags Flags
+	ctx.request.query = parseQuery("debug&verbose=&dry=false")
+	if err := ctx.BindQuery(&flags); err != nil {
+		t.Fatalf("expected bare flags to bind, got %v", err)
+	}
+	if !flags.Debug || flags.Verbose == nil || !*flags.Verbose || flags.Dry {
+		t.Fatalf("expected debug and verbose to be on and dry off, got %+v", flags)
+	}
+
+	var invalid QueryType
+	ctx.request.query = parseQuery("ids=1&ids=two")
+	if err := ctx.BindQuery(&invalid); err == nil {
+		t.Fatalf("expected error for invalid slice element")
+	}
+}
+
+func TestContextBindForm(t *testing.T) {
+	type FormType struct {
+		Name    string   `json:"name"`
+		Age     int      `json:"age"`
+		Hobbies []string `json:"hobby"`
+	}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    FormType
+		expectedErr string
+	}{
+		{
+			name:        "Valid form body",
+			contentType: MimeTypeFormUrlEncoded,
+			body:        "name=John+Doe&age=30&hobby=chess&hobby=go",
+			expected:    FormType{Name: "John Doe", Age: 30, Hobbies: []string{"chess", "go"}},
+		},
+		{
+			name:        "Content type with charset",
+			contentType: MimeTypeFormUrlEncoded + "; charset=utf-8",
+			body:        "name=Jane",
+			expected:    FormType{Name: "Jane"},
+		},
+		{
+			name:        "Invalid integer conversion",
+			contentType: MimeTypeFormUrlEncoded,
+			body:        "age=old",
+			expectedErr: `invalid value "old" for field "age": expected int`,
+		},
+		{
+			name:        "Unsupported content type",
+			contentType: MimeTypeJSON,
+			body:        `{"name": "John"}`,
+			expectedErr: "expected Content-Type " + MimeTypeFormUrlEncoded + " or " + MimeTypeMultipartForm,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := RequestContext{
+				request: HttpRequest{
+					headers: map[string]string{HeaderContentType: tc.contentType},
+					body:    []byte(tc.body),
+				},
+			}
+
+			var form FormType
+			err := ctx.BindForm(&form)
+			if tc.expectedErr == "" && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tc.expectedErr != "" {
+				if err == nil || bindErrorMessage(err) != tc.expectedErr {
+					t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(form, tc.expected) {
+				t.Fatalf("expected form %+v, got %+v", tc.expected, form)
+			}
+		})
+	}
+}
+
+func TestFormValue(t *testing.T) {
+	ctx := RequestContext{
+		request: HttpRequest{
+			headers: map[string]string{"content-type": MimeTypeFormUrlEncoded},
+			body:    []byte("key1=value1&key1=value2&empty="),
+		},
+	}
+
+	value, exists := ctx.FormValue("key1")
+	if !exists || value != "value1" {
+		t.Fatalf("expected value 'value1', got '%s'", value)
+	}
+
+	value, exists = ctx.FormValue("empty")
+	if !exists || value != "" {
+		t.Fatalf("expected empty value to exist, got '%s'", value)
+	}
+
+	_, exists = ctx.FormValue("key2")
+	if exists {
+		t.Fatalf("expected key2 to not exist")
+	}
+}
+
+func TestContextBindBodyContentType(t *testing.T) {
+	type BodyType struct {
+		Key1 string `json:"key1" xml:"key1"`
+		Key2 string `json:"key2" xml:"key2"`
+	}
+
+	engine := New()
+	engine.RegisterDecoder("Application/X-Custom", func(ctx Context, v any) error {
+		v.(*BodyType).Key1 = string(ctx.Body())
+		return nil
+	})
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    BodyType
+		statusCode  int
+	}{
+		{
+			name:        "JSON with charset",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"key1": "value1", "key2": "value2"}`,
+			expected:    BodyType{Key1: "value1", Key2: "value2"},
+		},
+		{
+			name:        "Structured syntax suffix falls back to JSON",
+			contentType: "application/vnd.api+json",
+			body:        `{"key1": "value1"}`,
+			expected:    BodyType{Key1: "value1"},
+		},
+		{
+			name:        "XML body",
+			contentType: MimeTypeXML,
+			body:        `<BodyType><key1>value1</key1><key2>value2</key2></BodyType>`,
+			expected:    BodyType{Key1: "value1", Key2: "value2"},
+		},
+		{
+			name:        "Form body",
+			contentType: MimeTypeFormUrlEncoded,
+			body:        "key1=value1&key2=value2",
+			expected:    BodyType{Key1: "value1", Key2: "value2"},
+		},
+		{
+			name:        "Custom registered decoder",
+			contentType: "application/x-custom",
+			body:        "raw",
+			expected:    BodyType{Key1: "raw"},
+		},
+		{
+			name:        "Unsupported media type",
+			contentType: "application/cbor",
+			body:        "\xa1",
+			statusCode:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := RequestContext{
+				request: HttpRequest{
+					headers: map[string]string{HeaderContentType: tc.contentType},
+					body:    []byte(tc.body),
+				},
+				engine: &engine,
+			}
+
+			var body BodyType
+			err := ctx.BindBody(&body)
+			if tc.statusCode != 0 {
+				httpErr, ok := err.(HttpError)
+				if !ok || httpErr.StatusCode != tc.statusCode {
+					t.Fatalf("expected HttpError with status %d, got %v", tc.statusCode, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if body != tc.expected {
+				t.Fatalf("expected body %v, got %v", tc.expected, body)
+			}
+		})
+	}
+}
+
+func TestContextBind(t *testing.T) {
+	type CreateOrder struct {
+		UserId   int      `path:"userId"`
+		DryRun   bool     `query:"dryRun"`
+		Tags     []string `query:"tag"`
+		Currency string   `query:"currency" default:"EUR"`
+		Token    string   `header:"X-Api-Token" validate:"required"`
+		Item     string   `json:"item" validate:"required"`
+		Note     string   `json:"note" query:"note" default:"none"`
+		Label    string   `json:"label" default:"anon"`
+		Quantity int      `json:"quantity" validate:"min=1"`
+	}
+
+	newCtx := func(path map[string]string, query string, body string) *RequestContext {
+		return &RequestContext{
+			request: HttpRequest{
+				pathParams: path,
+				query:      parseQuery(query),
+				headers: map[string]string{
+					"x-api-token":     "secret",
+					HeaderContentType: MimeTypeJSON,
+				},
+				body: []byte(body),
+			},
+		}
+	}
+
+	t.Run("Fills every source", func(t *testing.T) {
+		ctx := newCtx(map[string]string{"userId": "42"}, "dryRun=true&tag=a&tag=b", `{"item": "book", "quantity": 2}`)
+
+		var req CreateOrder
+		if err := ctx.Bind(&req); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		expected := CreateOrder{UserId: 42, DryRun: true, Tags: []string{"a", "b"}, Currency: "EUR", Token: "secret", Item: "book", Quantity: 2, Note: "none", Label: "anon"}
+		if !reflect.DeepEqual(req, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, req)
+		}
+	})
+
+	t.Run("Reports all binding errors together", func(t *testing.T) {
+		ctx := newCtx(map[string]string{"userId": "abc"}, "dryRun=maybe", `{"item": "book", "quantity": 1}`)
+
+		var req CreateOrder
+		err := ctx.Bind(&req)
+
+		httpErr, ok := err.(HttpError)
+		if !ok || httpErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 HttpError, got %v", err)
+		}
+
+		var bindErrs BindErrors
+		if !errors.As(err, &bindErrs) || len(bindErrs) != 2 || bindErrs[0].Field != "userId" || bindErrs[1].Field != "dryRun" {
+			t.Fatalf("expected errors for userId and dryRun, got %v", err)
+		}
+	})
+
+	t.Run("Defaults don't override the body", func(t *testing.T) {
+		ctx := newCtx(nil, "", `{"item": "book", "quantity": 1, "note": "gift"}`)
+
+		var req CreateOrder
+		if err := ctx.Bind(&req); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if req.Note != "gift" || req.Currency != "EUR" || req.Label != "anon" {
+			t.Fatalf("expected note from the body and default currency and label, got %+v", req)
+		}
+	})
+
+	t.Run("Reports a malformed body with the other errors", func(t *testing.T) {
+		ctx := newCtx(map[string]string{"userId": "abc"}, "", `{"item": `)
+
+		var req CreateOrder
+		err := ctx.Bind(&req)
+
+		httpErr, ok := err.(HttpError)
+		if !ok || httpErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 HttpError, got %v", err)
+		}
+		var bindErrs BindErrors
+		if !errors.As(err, &bindErrs) || len(bindErrs) != 1 || bindErrs[0].Field != "userId" {
+			t.Fatalf("expected error for userId, got %v", err)
+		}
+		if !strings.Contains(httpErr.Body, `"detail"`) || !strings.Contains(httpErr.Body, `"field":"userId"`) {
+			t.Fatalf("expected body to report the malformed body and userId, got %s", httpErr.Body)
+		}
+	})
+
+	t.Run("Validates once after binding", func(t *testing.T) {
+		ctx := newCtx(nil, "", `{"quantity": 0}`)
+
+		var req CreateOrder
+		err := ctx.Bind(&req)
+
+		var validationErrs ValidationErrors
+		if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Path != "item" {
+			t.Fatalf("expected item to be required, got %v", err)
+		}
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		ctx := newCtx(nil, "", `{"item": `)
+
+		var req CreateOrder
+		err := ctx.Bind(&req)
+
+		httpErr, ok := err.(HttpError)
+		if !ok || httpErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 HttpError, got %v", err)
+		}
+	})
+}
+
+func TestContextNextAndAbort(t *testing.T) {
+	var calls []string
+	record := func(name string) HttpHandler {
+		return func(ctx Context) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	failing := errors.New("handler failed")
+
+	testCases := []struct {
+		name          string
+		handlers      []HttpHandler
+		expectedCalls []string
+		expectedErr   error
+		aborted       bool
+	}{
+		{
+			name:          "Return style handlers run in order",
+			handlers:      []HttpHandler{record("first"), record("second")},
+			expectedCalls: []string{"first", "second"},
+		},
+		{
+			name: "Next wraps the rest of the chain",
+			handlers: []HttpHandler{
+				func(ctx Context) error {
+					calls = append(calls, "before")
+					err := ctx.Next()
+					calls = append(calls, "after")
+					return err
+				},
+				record("handler"),
+			},
+			expectedCalls: []string{"before", "handler", "after"},
+		},
+		{
+			name: "Errors from Next are returned to the wrapping middleware",
+			handlers: []HttpHandler{
+				func(ctx Context) error {
+					err := ctx.Next()
+					calls = append(calls, "saw "+err.Error())
+					return err
+				},
+				func(ctx Context) error { return failing },
+				record("never"),
+			},
+			expectedCalls: []string{"saw handler failed"},
+			expectedErr:   failing,
+		},
+		{
+			name: "Abort stops the chain without an error",
+			handlers: []HttpHandler{
+				func(ctx Context) error {
+					calls = append(calls, "auth")
+					ctx.Abort()
+					return nil
+				},
+				record("never"),
+			},
+			expectedCalls: []string{"auth"},
+			aborted:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = nil
+			ctx := RequestContext{handlers: tc.handlers}
+
+			err := ctx.Next()
+			if err != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(calls, tc.expectedCalls) {
+				t.Fatalf("expected calls %v, got %v", tc.expectedCalls, calls)
+			}
+			if ctx.IsAborted() != tc.aborted {
+				t.Fatalf("expected aborted %v, got %v", tc.aborted, ctx.IsAborted())
+			}
+		})
+	}
+}
+
+// bindErrorMessage returns the cause wrapped in the 400 HttpError a Bind method returns for bad input, or the message of any other error
+func bindErrorMessage(err error) string {
+	if httpErr, ok := err.(HttpError); ok && httpErr.StatusCode == http.StatusBadRequest && httpErr.Err != nil {
+		return httpErr.Err.Error()
+	}
+	return err.Error()
+}
+
+func TestBindFailuresRespondWithBadRequest(t *testing.T) {
+	type ListQuery struct {
+		Page int `query:"page"`
+	}
+
+	w := newTestServer()
+	w.GET("/items", func(ctx Context) error {
+		var query ListQuery
+		if err := ctx.BindQuery(&query); err != nil {
+			return err
+		}
+		return ctx.String(200, "ok")
+	})
+
+	w.POST("/items", func(ctx Context) error {
+		var body map[string]any
+		if err := ctx.BindBody(&body); err != nil {
+			return err
+		}
+		return ctx.String(200, "ok")
+	})
+
+	response := serve(t, w, "GET /items?page=abc HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 400 Bad Request") || !strings.Contains(response, `"field":"page"`) || !strings.Contains(response, `"value":"abc"`) {
+		t.Errorf("expected a 400 describing the field, got %q", response)
+	}
+
+	response = serve(t, w, "POST /items HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\nContent-Length: 5\r\n\r\n{bad}")
+	if !strings.HasPrefix(response, "HTTP/1.1 400 Bad Request") || !strings.Contains(response, `"error":"invalid request body"`) {
+		t.Errorf("expected a 400 for a malformed body, got %q", response)
+	}
+}
@@ -1,15 +1,249 @@
 package whiskey
 
 import (
+	"encoding"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// BindError is returned when a value can't be converted to the type of the field it's bound to
+type BindError struct {
+	Field string // Key the value was read from, e.g. `age` or `filter.name`
+	Value string
+	Err   error
+}
+
+func (b *BindError) Error() string {
+	return fmt.Sprintf("invalid value %q for field %q: %v", b.Value, b.Field, b.Err)
+}
+
+func (b *BindError) Unwrap() error {
+	return b.Err
+}
+
+// BindErrors collects every value that couldn't be bound to a struct, so clients can fix all of them at once
+type BindErrors []*BindError
+
+func (b BindErrors) Error() string {
+	messages := make([]string, len(b))
+	for idx, bindErr := range b {
+		messages[idx] = bindErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (b BindErrors) Unwrap() []error {
+	errs := make([]error, len(b))
+	for idx, bindErr := range b {
+		errs[idx] = bindErr
+	}
+	return errs
+}
+
+// httpError converts the binding failures into a 400 HttpError whose body lists every failure
+func (b BindErrors) httpError() HttpError {
+	fields := make([]Json, len(b))
+	for idx, bindErr := range b {
+		fields[idx] = Json{"field": bindErr.Field, "value": bindErr.Value, "error": bindErr.Err.Error()}
+	}
+
+	httpErr := newHTTPErrorWithFields(http.StatusBadRequest, "invalid request", Json{"fields": fields})
+	httpErr.Err = b
+	return httpErr
+}
+
+// bindSource describes where bound values come from and how struct fields name their keys
+type bindSource struct {
+	tag          string              // Struct tag holding the key of a field. Fields without it fall back to their `json` tag
+	strict       bool                // Only bind fields that have the tag of this source, without falling back to `json`. Used when several sources fill one struct
+	normalizeKey func(string) string // Applied to keys before they are matched. nil matches keys exactly
+	defaults     defaultPolicy       // When fields whose key is absent get the value of their `default` tag
+}
+
+type defaultPolicy int
+
+const (
+	defaultsAlways defaultPolicy = iota // Every field whose key is absent gets its default
+	defaultsNever                       // Defaults are left for a later pass, once every source has been bound
+	defaultsIfZero                      // Only fields that are still zero get their default, so values set by other sources are kept
+)
+
+var (
+	jsonSource   = bindSource{tag: "json"}
+	querySource  = bindSource{tag: "query"}
+	pathSource   = bindSource{tag: "path"}
+	formSource   = bindSource{tag: "form"}
+	headerSource = bindSource{tag: "header", normalizeKey: textproto.CanonicalMIMEHeaderKey} // Header names are case insensitive
+)
+
+func (b bindSource) normalize(key string) string {
+	if b.normalizeKey == nil {
+		return key
+	}
+	return b.normalizeKey(key)
+}
+
+// only returns a strict copy of the source that binds nothing but fields carrying its own tag
+func (b bindSource) only() bindSource {
+	b.strict = true
+	return b
+}
+
+// withDefaults returns a copy of the source that applies `default` tags according to policy
+func (b bindSource) withDefaults(policy defaultPolicy) bindSource {
+	b.defaults = policy
+	return b
+}
+
+// useDefault reports whether a field whose key is absent gets the value of its `default` tag
+func (b bindSource) useDefault(info fieldInfo, field reflect.Value) bool {
+	switch b.defaults {
+	case defaultsNever:
+		return false
+	case defaultsIfZero:
+		return info.hasDefault && field.IsZero()
+	default:
+		return info.hasDefault
+	}
+}
+
+// fieldKey returns the key a field is bound from. ok is false when the field has no key for this source
+func (b bindSource) fieldKey(field reflect.StructField) (key string, ok bool) {
+	tag, found := field.Tag.Lookup(b.tag)
+	if !found && !b.strict {
+		tag = field.Tag.Get("json")
+	}
+
+	// Remove anything after a comma in the tag (handles omitempty, etc.)
+	key, _, _ = strings.Cut(tag, ",")
+	if key == "" || key == "-" {
+		return "", false
+	}
+	return key, true
+}
+
+type fieldKind int
+
+const (
+	fieldScalar      fieldKind = iota // Parsed from the first value of its key
+	fieldSlice                        // Parsed from every value of its key
+	fieldStruct                       // Nested struct filled from dotted keys
+	fieldStructPtr                    // Optional nested struct, only allocated when one of its keys is present
+	fieldEmbedded                     // Embedded struct whose fields are promoted
+	fieldEmbeddedPtr                  // Embedded pointer to a struct whose fields are promoted
+)
+
+// fieldInfo is the binding metadata of a single struct field
+type fieldInfo struct {
+	index        int
+	key          string
+	kind         fieldKind
+	defaultValue string
+	hasDefault   bool
+}
+
+// structInfo is the binding metadata of a struct type for one source. It only depends on the type, so it's computed once and cached
+type structInfo struct {
+	fields []fieldInfo
+}
+
+type structInfoKey struct {
+	structType reflect.Type
+	tag        string
+	strict     bool
+}
+
+var structInfoCache sync.Map // structInfoKey -> *structInfo
+
+func getStructInfo(structType reflect.Type, source bindSource) *structInfo {
+	cacheKey := structInfoKey{structType: structType, tag: source.tag, strict: source.strict}
+	if info, ok := structInfoCache.Load(cacheKey); ok {
+		return info.(*structInfo)
+	}
+
+	info := &structInfo{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		// Fields of untagged embedded structs are promoted, so they are read with the same prefix as the parent
+		if field.Anonymous && field.Tag.Get(source.tag) == "" && field.Tag.Get("json") == "" {
+			switch {
+			case field.Type.Kind() == reflect.Struct:
+				info.fields = append(info.fields, fieldInfo{index: i, kind: fieldEmbedded})
+			case field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct && field.IsExported():
+				info.fields = append(info.fields, fieldInfo{index: i, kind: fieldEmbeddedPtr})
+			}
+			continue
+		}
+
+		key, hasKey := source.fieldKey(field)
+		if !hasKey || !field.IsExported() {
+			continue // Skip if we can't set this field
+		}
+
+		defaultValue, hasDefault := field.Tag.Lookup("default")
+		info.fields = append(info.fields, fieldInfo{
+			index:        i,
+			key:          key,
+			kind:         kindOf(field.Type),
+			defaultValue: defaultValue,
+			hasDefault:   hasDefault,
+		})
+	}
+
+	actual, _ := structInfoCache.LoadOrStore(cacheKey, info)
+	return actual.(*structInfo)
+}
+
+func kindOf(fieldType reflect.Type) fieldKind {
+	switch {
+	case isNestedStruct(fieldType):
+		return fieldStruct
+	case fieldType.Kind() == reflect.Ptr && isNestedStruct(fieldType.Elem()):
+		return fieldStructPtr
+	case fieldType.Kind() == reflect.Slice && !isTextType(fieldType):
+		return fieldSlice
+	default:
+		return fieldScalar
+	}
+}
+
 // MapToStruct takes a map and converts it to a struct based on `json` tags
 // We aren't converting map to json string and back to json since we also need to do type casting of string to the field type
 func mapToStruct(m map[string]string, s interface{}) error {
+	return mapToStructFrom(m, s, jsonSource)
+}
+
+// mapToStructFrom is mapToStruct with the keys named by the tag of the given source
+func mapToStructFrom(m map[string]string, s interface{}, source bindSource) error {
+	return valuesToStructFrom(singleValues(m), s, source)
+}
+
+func singleValues(m map[string]string) map[string][]string {
+	values := make(map[string][]string, len(m))
+	for key, value := range m {
+		values[key] = []string{value}
+	}
+	return values
+}
+
+// valuesToStructFrom fills a struct from a map that can hold multiple values per key with the keys named by the tag of the given source.
+// Slice fields receive every value of their key, scalar fields receive the first one and nested structs are filled from dotted keys (`filter.name`).
+// Fields whose key is absent get the value of their `default` tag, if any. Every value that fails to convert is reported in BindErrors
+func valuesToStructFrom(values map[string][]string, s interface{}, source bindSource) error {
 	// Check if s is a pointer
 	value := reflect.ValueOf(s)
 	if value.Kind() != reflect.Ptr {
@@ -24,67 +258,222 @@ func mapToStruct(m map[string]string, s interface{}) error {
 		return errors.New("s must be a pointer to a struct")
 	}
 
-	// Loop through the fields of the struct
-	for i := 0; i < value.NumField(); i++ {
-		field := value.Type().Field(i)
-		jsonTag := field.Tag.Get("json")
-		if jsonTag == "" {
-			continue
+	if source.normalizeKey != nil {
+		normalized := make(map[string][]string, len(values))
+		for key, vals := range values {
+			normalized[source.normalize(key)] = vals
 		}
+		values = normalized
+	}
+
+	var errs BindErrors
+	setStructFields(values, value, "", source, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
-		// Remove anything after a comma in the json tag (handles omitempty, etc.)
-		if commaIdx := strings.Index(jsonTag, ","); commaIdx > 0 {
-			jsonTag = jsonTag[:commaIdx]
+// bodySources name the fields of decoded bodies, which get their `default` tags once decoding is done
+var bodySources = []bindSource{jsonSource, formSource}
+
+// applyBodyDefaults gives the fields of a decoded body that are still zero the value of their `default` tag.
+// Bodies decoded into anything other than a struct, like a map, have no defaults
+func applyBodyDefaults(body any) error {
+	value := reflect.ValueOf(body)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs BindErrors
+	for _, source := range bodySources {
+		if bindErrs, ok := valuesToStructFrom(nil, body, source.withDefaults(defaultsIfZero)).(BindErrors); ok {
+			errs = append(errs, bindErrs...)
 		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func setStructFields(values map[string][]string, value reflect.Value, prefix string, source bindSource, errs *BindErrors) {
+	info := getStructInfo(value.Type(), source)
 
-		// Check if the map has the key
-		if val, ok := m[jsonTag]; ok {
-			srcField := value.Field(i)
-			if !srcField.CanSet() {
-				continue // Skip if we can't set this field
+	// Fields inside a nested struct belong to the source the struct was bound from, so they may fall back to `json` tags
+	nestedSource := source
+	nestedSource.strict = false
+
+	// Loop through the fields of the struct
+	for _, fieldInfo := range info.fields {
+		field := value.Field(fieldInfo.index)
+		key := prefix + fieldInfo.key
+
+		switch fieldInfo.kind {
+		case fieldEmbedded:
+			setStructFields(values, field, prefix, source, errs)
+		case fieldEmbeddedPtr:
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			setStructFields(values, field.Elem(), prefix, source, errs)
+		case fieldStruct:
+			setStructFields(values, field, key+".", nestedSource, errs)
+		case fieldStructPtr:
+			// Optional nested structs are only allocated when at least one of their keys is present
+			if !hasKeyWithPrefix(values, source.normalize(key+".")) {
+				continue
+			}
+			nested := reflect.New(field.Type().Elem())
+			setStructFields(values, nested.Elem(), key+".", nestedSource, errs)
+			field.Set(nested)
+		case fieldSlice:
+			vals, ok := values[source.normalize(key)]
+			if !ok && source.useDefault(fieldInfo, field) {
+				// Defaults of slice fields are comma separated
+				vals, ok = strings.Split(fieldInfo.defaultValue, ","), true
+			}
+			if !ok {
+				continue
 			}
 
-			srcValue, err := convertValueToType(val, srcField)
-			if err != nil {
-				return err
+			slice := reflect.MakeSlice(field.Type(), 0, len(vals))
+			failed := false
+			for _, val := range vals {
+				elemValue, err := convertValueToType(val, field.Type().Elem())
+				if err != nil {
+					*errs = append(*errs, &BindError{Field: key, Value: val, Err: err})
+					failed = true
+					continue
+				}
+				slice = reflect.Append(slice, elemValue)
+			}
+			if !failed {
+				field.Set(slice)
+			}
+		default:
+			// Check if the map has the key
+			vals, ok := values[source.normalize(key)]
+			if !ok && source.useDefault(fieldInfo, field) {
+				vals, ok = []string{fieldInfo.defaultValue}, true
+			}
+			if !ok || len(vals) == 0 {
+				continue
 			}
 
-			// Set the value in the struct
-			if srcValue.IsValid() {
-				srcField.Set(srcValue)
-			} else {
-				return errors.New("invalid value type")
+			srcValue, err := convertValueToType(vals[0], field.Type())
+			if err != nil {
+				*errs = append(*errs, &BindError{Field: key, Value: vals[0], Err: err})
+				continue
 			}
 
+			// Set the value in the struct
+			field.Set(srcValue)
 		}
 	}
+}
 
-	return nil
+// isTextType reports whether values of t are parsed from a single string through encoding.TextUnmarshaler
+func isTextType(t reflect.Type) bool {
+	return t.Implements(textUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// isNestedStruct reports whether t is a struct whose fields are bound individually rather than parsed from a single value
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !isTextType(t)
 }
 
-func convertValueToType(value string, field reflect.Value) (reflect.Value, error) {
-	switch field.Type().Kind() {
+func hasKeyWithPrefix(values map[string][]string, prefix string) bool {
+	for key := range values {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// convertValueToType parses value into a value of fieldType. Pointers are allocated so optional fields stay nil unless a value is sent
+func convertValueToType(value string, fieldType reflect.Type) (reflect.Value, error) {
+	if fieldType.Kind() == reflect.Ptr {
+		elem, err := convertValueToType(value, fieldType.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(fieldType.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	}
+
+	result := reflect.New(fieldType).Elem()
+
+	switch {
+	case fieldType == timeType:
+		t, err := parseTime(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.Set(reflect.ValueOf(t))
+		return result, nil
+	case fieldType == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return reflect.Value{}, errors.New("expected a duration like 1h30m")
+		}
+		result.SetInt(int64(d))
+		return result, nil
+	case reflect.PointerTo(fieldType).Implements(textUnmarshalerType):
+		if err := result.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return reflect.Value{}, err
+		}
+		return result, nil
+	}
+
+	switch fieldType.Kind() {
 	case reflect.String:
-		return reflect.ValueOf(value), nil
-	case reflect.Int:
-		intValue, err := strconv.Atoi(value)
+		result.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, err := strconv.ParseInt(value, 10, fieldType.Bits())
 		if err != nil {
-			return reflect.Value{}, nil
+			return reflect.Value{}, fmt.Errorf("expected %s", fieldType.Kind())
 		}
-		return reflect.ValueOf(intValue), nil
+		result.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, err := strconv.ParseUint(value, 10, fieldType.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("expected %s", fieldType.Kind())
+		}
+		result.SetUint(uintValue)
 	case reflect.Bool:
+		// A key without a value, like the bare flag `?debug`, turns the flag on
+		if value == "" {
+			result.SetBool(true)
+			break
+		}
 		boolValue, err := strconv.ParseBool(value)
 		if err != nil {
-			return reflect.Value{}, nil
+			return reflect.Value{}, errors.New("expected bool")
 		}
-		return reflect.ValueOf(boolValue), nil
-	case reflect.Float64:
-		floatValue, err := strconv.ParseFloat(value, 64)
+		result.SetBool(boolValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(value, fieldType.Bits())
 		if err != nil {
-			return reflect.Value{}, nil
+			return reflect.Value{}, fmt.Errorf("expected %s", fieldType.Kind())
 		}
-		return reflect.ValueOf(floatValue), nil
+		result.SetFloat(floatValue)
 	default:
-		return reflect.Value{}, errors.New("unsupported type")
+		return reflect.Value{}, fmt.Errorf("unsupported type %s", fieldType)
+	}
+
+	return result, nil
+}
+
+// parseTime accepts RFC 3339 timestamps as well as plain dates
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
 	}
+	return time.Time{}, errors.New("expected an RFC 3339 timestamp or a date like 2006-01-02")
 }
@@ -1,8 +1,11 @@
 package whiskey
 
 import (
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type TestStruct struct {
@@ -114,3 +117,227 @@ func TestMapToStruct(t *testing.T) {
 		})
 	}
 }
+
+type upperString string
+
+func (u *upperString) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		return errors.New("empty value")
+	}
+	*u = upperString(strings.ToUpper(string(text)))
+	return nil
+}
+
+type Pagination struct {
+	Page  uint16 `json:"page"`
+	Limit uint8  `json:"limit"`
+}
+
+type RichStruct struct {
+	Pagination
+	Int8     int8          `json:"int8"`
+	Int64    int64         `json:"int64"`
+	Uint     uint          `json:"uint"`
+	Float32  float32       `json:"float32"`
+	Created  time.Time     `json:"created"`
+	Day      time.Time     `json:"day"`
+	Timeout  time.Duration `json:"timeout"`
+	Optional *int          `json:"optional"`
+	Missing  *string       `json:"missing"`
+	Upper    upperString   `json:"upper"`
+	Uppers   []upperString `json:"uppers"`
+	Times    []time.Time   `json:"times"`
+	Filter   *struct {
+		Name string `json:"name"`
+	} `json:"filter"`
+	NoFilter *struct {
+		Name string `json:"name"`
+	} `json:"noFilter"`
+	Ignored string `json:"-"`
+}
+
+func TestMapToStructRichTypes(t *testing.T) {
+	values := map[string][]string{
+		"page":        {"3"},
+		"limit":       {"50"},
+		"int8":        {"-12"},
+		"int64":       {"9223372036854775807"},
+		"uint":        {"42"},
+		"float32":     {"1.5"},
+		"created":     {"2024-05-01T10:00:00Z"},
+		"day":         {"2024-05-01"},
+		"timeout":     {"1m30s"},
+		"optional":    {"7"},
+		"upper":       {"abc"},
+		"uppers":      {"a", "b"},
+		"times":       {"2024-01-01", "2024-01-02"},
+		"filter.name": {"john"},
+		"-":           {"ignored"},
+	}
+
+	var s RichStruct
+	if err := valuesToStructFrom(values, &s, jsonSource); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	switch {
+	case s.Page != 3 || s.Limit != 50:
+		t.Errorf("Expected embedded pagination {3 50}, got %+v", s.Pagination)
+	case s.Int8 != -12 || s.Int64 != 9223372036854775807 || s.Uint != 42 || s.Float32 != 1.5:
+		t.Errorf("Unexpected numeric values %+v", s)
+	case !s.Created.Equal(created) || !s.Day.Equal(day):
+		t.Errorf("Unexpected times %v %v", s.Created, s.Day)
+	case s.Timeout != 90*time.Second:
+		t.Errorf("Expected timeout 1m30s, got %v", s.Timeout)
+	case s.Optional == nil || *s.Optional != 7:
+		t.Errorf("Expected optional to be 7, got %v", s.Optional)
+	case s.Missing != nil:
+		t.Errorf("Expected missing to stay nil, got %v", *s.Missing)
+	case s.Upper != "ABC" || !reflect.DeepEqual(s.Uppers, []upperString{"A", "B"}):
+		t.Errorf("Expected text unmarshaler to be used, got %v %v", s.Upper, s.Uppers)
+	case len(s.Times) != 2 || s.Times[1].Day() != 2:
+		t.Errorf("Unexpected times slice %v", s.Times)
+	case s.Filter == nil || s.Filter.Name != "john":
+		t.Errorf("Expected filter to be allocated, got %+v", s.Filter)
+	case s.NoFilter != nil:
+		t.Errorf("Expected noFilter to stay nil, got %+v", s.NoFilter)
+	case s.Ignored != "":
+		t.Errorf("Expected ignored field to stay empty, got %v", s.Ignored)
+	}
+}
+
+func TestMapToStructErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string][]string
+		wantErr string
+	}{
+		{
+			name:    "Overflowing int8",
+			values:  map[string][]string{"int8": {"300"}},
+			wantErr: `invalid value "300" for field "int8": expected int8`,
+		},
+		{
+			name:    "Negative unsigned",
+			values:  map[string][]string{"uint": {"-1"}},
+			wantErr: `invalid value "-1" for field "uint": expected uint`,
+		},
+		{
+			name:    "Invalid time",
+			values:  map[string][]string{"created": {"yesterday"}},
+			wantErr: `invalid value "yesterday" for field "created": expected an RFC 3339 timestamp or a date like 2006-01-02`,
+		},
+		{
+			name:    "Invalid duration",
+			values:  map[string][]string{"timeout": {"soon"}},
+			wantErr: `invalid value "soon" for field "timeout": expected a duration like 1h30m`,
+		},
+		{
+			name:    "Text unmarshaler error",
+			values:  map[string][]string{"uppers": {"a", ""}},
+			wantErr: `invalid value "" for field "uppers": empty value`,
+		},
+		{
+			name:    "Embedded field error",
+			values:  map[string][]string{"page": {"x"}},
+			wantErr: `invalid value "x" for field "page": expected uint16`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s RichStruct
+			err := valuesToStructFrom(tt.values, &s, jsonSource)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+			}
+
+			var bindErr *BindError
+			if !errors.As(err, &bindErr) {
+				t.Fatalf("Expected a BindError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestBindSourceTagsAndDefaults(t *testing.T) {
+	type Request struct {
+		Search   string   `json:"search" query:"q"`
+		Page     int      `json:"page" default:"1"`
+		Limit    *int     `query:"limit" default:"20"`
+		Sort     []string `query:"sort" default:"name,-created"`
+		Internal string   `json:"internal" query:"-"`
+		Token    string   `header:"X-Api-Token"`
+		Agent    string   `json:"user-agent"`
+	}
+
+	t.Run("Query tags with json fallback and defaults", func(t *testing.T) {
+		var r Request
+		values := map[string][]string{"q": {"shoes"}, "search": {"ignored"}, "internal": {"secret"}}
+		if err := valuesToStructFrom(values, &r, querySource); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if r.Search != "shoes" || r.Page != 1 || r.Limit == nil || *r.Limit != 20 || r.Internal != "" {
+			t.Errorf("Unexpected request %+v", r)
+		}
+		if !reflect.DeepEqual(r.Sort, []string{"name", "-created"}) {
+			t.Errorf("Expected default sort, got %v", r.Sort)
+		}
+	})
+
+	t.Run("Present keys override defaults", func(t *testing.T) {
+		var r Request
+		values := map[string][]string{"page": {"3"}, "limit": {"5"}, "sort": {"price"}}
+		if err := valuesToStructFrom(values, &r, querySource); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if r.Page != 3 || *r.Limit != 5 || !reflect.DeepEqual(r.Sort, []string{"price"}) {
+			t.Errorf("Unexpected request %+v", r)
+		}
+	})
+
+	t.Run("Invalid default", func(t *testing.T) {
+		var r struct {
+			Page int `query:"page" default:"first"`
+		}
+		err := valuesToStructFrom(map[string][]string{}, &r, querySource)
+		if err == nil || err.Error() != `invalid value "first" for field "page": expected int` {
+			t.Fatalf("Expected invalid default error, got: %v", err)
+		}
+	})
+
+	t.Run("Header names ignore case", func(t *testing.T) {
+		var r Request
+		headers := map[string]string{"x-api-token": "abc", "User-Agent": "curl"}
+		if err := mapToStructFrom(headers, &r, headerSource); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if r.Token != "abc" || r.Agent != "curl" {
+			t.Errorf("Unexpected request %+v", r)
+		}
+	})
+}
+
+func BenchmarkValuesToStruct(b *testing.B) {
+	values := map[string][]string{
+		"page":        {"3"},
+		"limit":       {"50"},
+		"int64":       {"42"},
+		"created":     {"2024-05-01T10:00:00Z"},
+		"uppers":      {"a", "b"},
+		"filter.name": {"john"},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var s RichStruct
+		if err := valuesToStructFrom(values, &s, querySource); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package whiskey
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CorsConfig configures the CORS middleware
+type CorsConfig struct {
+	// AllowOrigins lists the origins allowed to make requests. An entry is either "*" for any origin, an exact origin like "https://example.com"
+	// or a wildcard subdomain like "https://*.example.com", which doesn't match example.com itself. Defaults to "*"
+	AllowOrigins    []string
+	AllowOriginFunc func(origin string) bool // Decides whether an origin is allowed. It's used instead of AllowOrigins when set
+
+	AllowMethods     []string      // Methods allowed in preflight requests. Defaults to GET, HEAD, POST, PUT, PATCH and DELETE
+	AllowHeaders     []string      // Request headers allowed in preflight requests. Defaults to the headers the preflight asks for
+	ExposeHeaders    []string      // Response headers browsers let scripts read, besides the CORS safelisted ones
+	AllowCredentials bool          // Allows cookies and authorization headers. Requires AllowOrigins without "*" or an AllowOriginFunc
+	MaxAge           time.Duration // How long browsers may cache preflight results. It's rounded down to seconds and omitted if zero. A negative value disables caching
+}
+
+var defaultCorsMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CorsMiddleware allows requests from any origin with the default CorsConfig
+func CorsMiddleware(ctx Context) error {
+	return defaultCors(ctx)
+}
+
+var defaultCors = CorsWithConfig(CorsConfig{})
+
+// CorsWithConfig returns a middleware that sets the CORS headers of responses to allowed origins and answers their preflight OPTIONS requests
+// with 204 No Content, without running the handlers after it. Requests from other origins are passed on without CORS headers, so browsers block them.
+// Register it with Use: preflights to routes without an OPTIONS handler are answered by the router, which only runs global middlewares.
+// It panics if AllowCredentials is set while any origin is allowed, since every site could then make authenticated requests
+func CorsWithConfig(config CorsConfig) HttpHandler {
+	if len(config.AllowOrigins) == 0 {
+		config.AllowOrigins = []string{"*"}
+	}
+	if config.AllowCredentials && config.AllowOriginFunc == nil && slices.Contains(config.AllowOrigins, "*") {
+		panic("whiskey: CORS with AllowCredentials requires explicit AllowOrigins or an AllowOriginFunc")
+	}
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = defaultCorsMethods
+	}
+
+	allowAnyOrigin := config.AllowOriginFunc == nil && slices.Contains(config.AllowOrigins, "*")
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+
+	allowed := config.AllowOriginFunc
+	if allowed == nil {
+		allowed = func(origin string) bool {
+			return slices.ContainsFunc(config.AllowOrigins, func(pattern string) bool {
+				return matchOrigin(pattern, origin)
+			})
+		}
+	}
+
+	return func(ctx Context) error {
+		response := ctx.Response()
+		origin, hasOrigin := ctx.GetHeader(HeaderOrigin)
+		preflight := ctx.Method() == http.MethodOptions && hasOrigin && hasHeader(ctx, HeaderAccessControlRequestMethod)
+
+		// Unless every origin gets the same "*" response, caches must keep responses for different origins apart
+		varyOrigin := !allowAnyOrigin
+		if varyOrigin {
+			response.AddHeaderValue(HeaderVary, HeaderOrigin)
+		}
+		if preflight {
+			response.AddHeaderValue(HeaderVary, HeaderAccessControlRequestMethod)
+			response.AddHeaderValue(HeaderVary, HeaderAccessControlRequestHeaders)
+		}
+
+		if !hasOrigin || !allowed(origin) {
+			if preflight {
+				response.SetStatusCode(http.StatusNoContent)
+				ctx.Abort()
+			}
+			return nil
+		}
+
+		if varyOrigin {
+			response.SetHeader(HeaderAccessControlAllowOrigin, origin)
+		} else {
+			response.SetHeader(HeaderAccessControlAllowOrigin, "*")
+		}
+		if config.AllowCredentials {
+			response.SetHeader(HeaderAccessControlAllowCredentials, "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				response.SetHeader(HeaderAccessControlExposeHeaders, exposeHeaders)
+			}
+			return nil
+		}
+
+		response.SetHeader(HeaderAccessControlAllowMethods, allowMethods)
+		if allowHeaders != "" {
+			response.SetHeader(HeaderAccessControlAllowHeaders, allowHeaders)
+		} else if requested, ok := ctx.GetHeader(HeaderAccessControlRequestHeaders); ok && requested != "" {
+			response.SetHeader(HeaderAccessControlAllowHeaders, requested)
+		}
+		if config.MaxAge > 0 {
+			response.SetHeader(HeaderAccessControlMaxAge, strconv.Itoa(int(config.MaxAge.Seconds())))
+		} else if config.MaxAge < 0 {
+			response.SetHeader(HeaderAccessControlMaxAge, "0")
+		}
+
+		response.SetStatusCode(http.StatusNoContent)
+		ctx.Abort()
+		return nil
+	}
+}
+
+// matchOrigin reports whether origin matches an AllowOrigins entry. Origins are compared ignoring case, since scheme and host are case insensitive
+func matchOrigin(pattern string, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	prefix, suffix, isWildcard := strings.Cut(pattern, "*")
+	if !isWildcard {
+		return strings.EqualFold(pattern, origin)
+	}
+
+	// https://*.example.com matches https://api.example.com, but not https://example.com or https://evil-example.com
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(suffix, ".") {
+		return false
+	}
+	if !strings.EqualFold(origin[:len(prefix)], prefix) || !strings.EqualFold(origin[len(origin)-len(suffix):], suffix) {
+		return false
+	}
+
+	subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(subdomain, "/:@")
+}
+
+func hasHeader(ctx Context, key string) bool {
+	_, ok := ctx.GetHeader(key)
+	return ok
+}
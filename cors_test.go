@@ -0,0 +1,182 @@
+package whiskey
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern string
+		origin  string
+		matches bool
+	}{
+		{pattern: "*", origin: "https://anything.dev", matches: true},
+		{pattern: "https://example.com", origin: "https://example.com", matches: true},
+		{pattern: "https://example.com", origin: "https://EXAMPLE.com", matches: true},
+		{pattern: "https://example.com", origin: "http://example.com"},
+		{pattern: "https://*.example.com", origin: "https://api.example.com", matches: true},
+		{pattern: "https://*.example.com", origin: "https://a.b.example.com", matches: true},
+		{pattern: "https://*.example.com", origin: "https://example.com"},
+		{pattern: "https://*.example.com", origin: "https://evil-example.com"},
+		{pattern: "https://*.example.com", origin: "https://evil.com/.example.com"},
+		{pattern: "https://*.example.com", origin: "http://api.example.com"},
+	}
+
+	for _, tt := range tests {
+		if matchOrigin(tt.pattern, tt.origin) != tt.matches {
+			t.Errorf("expected %s matching %s to be %v", tt.pattern, tt.origin, tt.matches)
+		}
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	handlerRan := false
+
+	w := newTestServer()
+	w.Use(CorsWithConfig(CorsConfig{
+		AllowOrigins:     []string{"https://*.example.com"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	w.POST("/users", func(ctx Context) error {
+		handlerRan = true
+		return ctx.String(201, "created")
+	})
+
+	response := serve(t, w, "OPTIONS /users HTTP/1.1\r\nHost: localhost\r\n"+
+		"Origin: https://app.example.com\r\n"+
+		"Access-Control-Request-Method: POST\r\n"+
+		"Access-Control-Request-Headers: Content-Type, X-Token\r\n\r\n")
+
+	expected := []string{
+		"HTTP/1.1 204 No Content",
+		"Access-Control-Allow-Origin: https://app.example.com\r\n",
+		"Access-Control-Allow-Methods: GET, POST\r\n",
+		"Access-Control-Allow-Headers: Content-Type, X-Token\r\n",
+		"Access-Control-Allow-Credentials: true\r\n",
+		"Access-Control-Max-Age: 600\r\n",
+		"Vary: Origin, Access-Control-Request-Method, Access-Control-Request-Headers\r\n",
+	}
+	for _, header := range expected {
+		if !strings.Contains(response, header) {
+			t.Errorf("expected %q in %q", header, response)
+		}
+	}
+	if handlerRan {
+		t.Error("expected the preflight to be answered without running the handler")
+	}
+}
+
+func TestCorsActualRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   CorsConfig
+		origin   string
+		expected []string
+		missing  []string
+	}{
+		{
+			name:     "any origin",
+			config:   CorsConfig{ExposeHeaders: []string{"X-Total"}},
+			origin:   "https://anything.dev",
+			expected: []string{"Access-Control-Allow-Origin: *\r\n", "Access-Control-Expose-Headers: X-Total\r\n"},
+			missing:  []string{"Vary:"},
+		},
+		{
+			name:     "allowed exact origin",
+			config:   CorsConfig{AllowOrigins: []string{"https://example.com"}},
+			origin:   "https://example.com",
+			expected: []string{"Access-Control-Allow-Origin: https://example.com\r\n", "Vary: Origin\r\n"},
+		},
+		{
+			name:     "disallowed origin",
+			config:   CorsConfig{AllowOrigins: []string{"https://example.com"}},
+			origin:   "https://evil.com",
+			expected: []string{"Vary: Origin\r\n"},
+			missing:  []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name: "origin function",
+			config: CorsConfig{AllowOriginFunc: func(origin string) bool {
+				return strings.HasSuffix(origin, ".internal")
+			}},
+			origin:   "http://dashboard.internal",
+			expected: []string{"Access-Control-Allow-Origin: http://dashboard.internal\r\n", "Vary: Origin\r\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestServer()
+			w.Use(CorsWithConfig(tt.config))
+			w.GET("/users", func(ctx Context) error {
+				return ctx.String(200, "users")
+			})
+
+			response := serve(t, w, "GET /users HTTP/1.1\r\nHost: localhost\r\nOrigin: "+tt.origin+"\r\n\r\n")
+			if !strings.HasSuffix(response, "users") {
+				t.Errorf("expected the handler to run, got %q", response)
+			}
+			for _, header := range tt.expected {
+				if !strings.Contains(response, header) {
+					t.Errorf("expected %q in %q", header, response)
+				}
+			}
+			for _, header := range tt.missing {
+				if strings.Contains(response, header) {
+					t.Errorf("expected no %q in %q", header, response)
+				}
+			}
+		})
+	}
+}
+
+func TestCorsMiddlewareDefaults(t *testing.T) {
+	w := newTestServer()
+	w.Use(CorsMiddleware)
+	w.GET("/users", func(ctx Context) error {
+		return ctx.String(200, "users")
+	})
+
+	response := serve(t, w, "OPTIONS /users HTTP/1.1\r\nHost: localhost\r\nOrigin: https://a.dev\r\nAccess-Control-Request-Method: DELETE\r\n\r\n")
+	if !strings.Contains(response, "Access-Control-Allow-Origin: *\r\n") || !strings.Contains(response, "Access-Control-Allow-Methods: GET, HEAD, POST, PUT, PATCH, DELETE\r\n") {
+		t.Errorf("unexpected preflight response %q", response)
+	}
+}
+
+func TestCorsCredentialsWithAnyOrigin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected CorsWithConfig to panic when credentials are allowed for any origin")
+		}
+	}()
+
+	CorsWithConfig(CorsConfig{AllowCredentials: true})
+}
+
+func TestOptionsRouting(t *testing.T) {
+	w := newTestServer()
+	w.GET("/users/{id}", func(ctx Context) error { return nil })
+	w.DELETE("/users/{id}", func(ctx Context) error { return nil })
+	w.OPTIONS("/custom", func(ctx Context) error {
+		return ctx.String(200, "custom options")
+	})
+
+	response := serve(t, w, "OPTIONS /users/1 HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 204 No Content") || !strings.Contains(response, "Allow: DELETE, GET, OPTIONS\r\n") {
+		t.Errorf("expected an automatic OPTIONS response, got %q", response)
+	}
+
+	response = serve(t, w, "OPTIONS /custom HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasSuffix(response, "custom options") {
+		t.Errorf("expected the registered OPTIONS handler to run, got %q", response)
+	}
+
+	response = serve(t, w, "OPTIONS /missing HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 404") {
+		t.Errorf("expected unknown paths to stay 404, got %q", response)
+	}
+}
@@ -1,7 +1,10 @@
 package whiskey
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 )
 
@@ -10,6 +13,9 @@ type HttpError struct {
 	Body       string
 	Message    string
 	BodyType   BodyType // Based on the type, sends a text/plain or application/json response
+	Err        error    // The underlying error, if any. It's never sent to the client
+
+	fields Json // Extra members of a JSON body next to "error". They become extensions when the error is sent as problem details
 }
 
 type BodyType int
@@ -26,6 +32,11 @@ func (h HttpError) Error() string {
 	return string(h.Body)
 }
 
+// Unwrap returns the underlying error so errors.Is and errors.As can look through an HttpError
+func (h HttpError) Unwrap() error {
+	return h.Err
+}
+
 // NewHttpError returns a response in format { "error": "<message_of_status_code>" } if bodyType is json else "<message_of_status_code>". The message_of_status_code is equal to `http.StatusText`
 func NewHttpError(statusCode int, bodyType BodyType) HttpError {
 	return NewHTTPErrorWithMessage(statusCode, http.StatusText(statusCode), bodyType)
@@ -33,38 +44,107 @@ func NewHttpError(statusCode int, bodyType BodyType) HttpError {
 
 // NewHTTPErrorWithMessage will return a response in the format "{ "error": "<message>" }" if bodyType is JSON else "<message>"
 func NewHTTPErrorWithMessage(statusCode int, message string, bodyType BodyType) HttpError {
-	var body string
-
-	switch bodyType {
-	case BodyTypeJSON:
-		body = fmt.Sprintf("{\"error\": \"%s\"}", message)
-	case BodyTypeString:
-	default:
-		body = message
+	if bodyType == BodyTypeJSON {
+		return newHTTPErrorWithFields(statusCode, message, nil)
 	}
 
 	return HttpError{
 		StatusCode: statusCode,
-		Body:       body,
+		Body:       message,
 		BodyType:   bodyType,
 		Message:    message,
 	}
 }
 
+// newHTTPErrorWithFields returns a JSON HttpError with the body { "error": "<message>" } extended with the given fields
+func newHTTPErrorWithFields(statusCode int, message string, fields Json) HttpError {
+	body := Json{"error": message}
+	maps.Copy(body, fields)
+
+	// The message is plain text and the fields are built by the framework from JSON friendly values, so this can't fail
+	b, _ := json.Marshal(body)
+
+	return HttpError{
+		StatusCode: statusCode,
+		Body:       string(b),
+		BodyType:   BodyTypeJSON,
+		Message:    message,
+		fields:     fields,
+	}
+}
+
 func defaultErrorHandler(err error, ctx Context) error {
 	if err == nil {
 		return nil
 	}
 
-	if httpErr, ok := err.(HttpError); ok {
+	useProblems := false
+	debug := false
+	reqCtx, isReqCtx := ctx.(*RequestContext)
+	if isReqCtx {
+		useProblems = reqCtx.config().ProblemDetails
+		debug = reqCtx.config().Debug
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		message := http.StatusText(http.StatusInternalServerError)
+		if debug {
+			message = fmt.Sprintf("%s\n\n%s", panicErr.Error(), panicErr.Stack)
+		}
+		if useProblems {
+			return writeProblem(ctx, NewProblem(http.StatusInternalServerError, message))
+		}
+		return ctx.String(http.StatusInternalServerError, message)
+	}
+
+	var problem Problem
+	if errors.As(err, &problem) {
+		return writeProblem(ctx, problem)
+	}
+
+	var httpErr HttpError
+	if errors.As(err, &httpErr) {
+		if useProblems {
+			return writeProblem(ctx, httpErr.Problem())
+		}
+
 		if httpErr.BodyType == BodyTypeString {
-			ctx.String(httpErr.StatusCode, httpErr.Body)
-		} else {
-			ctx.Json(httpErr.StatusCode, httpErr.Body)
+			return ctx.String(httpErr.StatusCode, httpErr.Body)
 		}
-	} else {
-		ctx.String(http.StatusInternalServerError, err.Error())
+		// Body already holds the encoded JSON, sending it through ctx.Json would encode it a second time
+		return ctx.Bytes(httpErr.StatusCode, MimeTypeJSON, withRequestID(ctx, []byte(httpErr.Body)))
 	}
 
-	return nil
+	// Unmapped errors may carry internal details, so the client only gets a generic response and the error is logged
+	if isReqCtx && reqCtx.engine != nil {
+		reqCtx.engine.errorLogger.Println("Unhandled error:", err)
+	}
+	if useProblems {
+		return writeProblem(ctx, NewProblem(http.StatusInternalServerError, ""))
+	}
+	return ctx.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+// withRequestID adds the ID of the request to a JSON object body so clients can quote it when reporting errors. Other bodies are returned as is
+func withRequestID(ctx Context, body []byte) []byte {
+	requestID, ok := ctx.GetString(RequestIDKey)
+	if !ok || requestID == "" {
+		return body
+	}
+
+	var members map[string]any
+	if err := json.Unmarshal(body, &members); err != nil {
+		return body
+	}
+	if _, exists := members["request_id"]; exists {
+		return body
+	}
+	members["request_id"] = requestID
+
+	b, err := json.Marshal(members)
+	if err != nil {
+		return body
+	}
+	return b
 }
@@ -0,0 +1,220 @@
+package whiskey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestNewHTTPErrorWithMessage(t *testing.T) {
+	jsonErr := NewHTTPErrorWithMessage(400, `name "bob" is taken`, BodyTypeJSON)
+	var body map[string]string
+	if err := json.Unmarshal([]byte(jsonErr.Body), &body); err != nil {
+		t.Fatalf("body %q is not valid JSON: %v", jsonErr.Body, err)
+	}
+	if body["error"] != `name "bob" is taken` {
+		t.Errorf("expected the message in the body, got %q", body["error"])
+	}
+
+	stringErr := NewHTTPErrorWithMessage(400, "bad request", BodyTypeString)
+	if stringErr.Body != "bad request" {
+		t.Errorf("expected the message as the body, got %q", stringErr.Body)
+	}
+}
+
+func TestProblemMarshalJSON(t *testing.T) {
+	problem := NewProblem(403, "not enough credit").
+		WithExtension("balance", 30).
+		WithExtension("status", 200)
+
+	b, err := json.Marshal(problem)
+	if err != nil {
+		t.Fatalf("failed to marshal problem: %v", err)
+	}
+
+	var members map[string]any
+	if err := json.Unmarshal(b, &members); err != nil {
+		t.Fatalf("failed to unmarshal problem: %v", err)
+	}
+
+	expected := map[string]any{
+		"type":    "about:blank",
+		"title":   "Forbidden",
+		"status":  float64(403),
+		"detail":  "not enough credit",
+		"balance": float64(30),
+	}
+	for key, value := range expected {
+		if members[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, members[key])
+		}
+	}
+	if _, ok := members["instance"]; ok {
+		t.Error("expected empty instance to be omitted")
+	}
+}
+
+func TestProblemUnwrap(t *testing.T) {
+	cause := errors.New("insufficient funds")
+	problem := NewProblem(403, "not enough credit")
+	problem.Err = cause
+
+	if !errors.Is(problem, cause) {
+		t.Error("expected problem to unwrap to its cause")
+	}
+	if problem.Error() != "not enough credit" {
+		t.Errorf("unexpected error message %q", problem.Error())
+	}
+}
+
+func TestDefaultErrorHandlerResponses(t *testing.T) {
+	tests := []struct {
+		name           string
+		problemDetails bool
+		err            error
+		contentType    string
+		body           string
+	}{
+		{
+			name:        "json http error is sent as is",
+			err:         NewHTTPErrorWithMessage(400, "invalid name", BodyTypeJSON),
+			contentType: MimeTypeJSON,
+			body:        `{"error":"invalid name"}`,
+		},
+		{
+			name:        "string http error",
+			err:         NewHTTPErrorWithMessage(400, "invalid name", BodyTypeString),
+			contentType: MimeTypeText,
+			body:        "invalid name",
+		},
+		{
+			name:        "problem",
+			err:         NewProblem(409, "already exists"),
+			contentType: MimeTypeProblemJSON,
+			body:        `{"detail":"already exists","status":409,"title":"Conflict","type":"about:blank"}`,
+		},
+		{
+			name:           "http error in problem mode",
+			problemDetails: true,
+			err:            newHTTPErrorWithFields(422, "validation failed", Json{"fields": []string{"name"}}),
+			contentType:    MimeTypeProblemJSON,
+			body:           `{"detail":"validation failed","fields":["name"],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`,
+		},
+		{
+			name:           "status text is not repeated as detail",
+			problemDetails: true,
+			err:            NewHttpError(404, BodyTypeJSON),
+			contentType:    MimeTypeProblemJSON,
+			body:           `{"status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestServer()
+			w.config.ProblemDetails = tt.problemDetails
+			w.GET("/fail", func(ctx Context) error {
+				return tt.err
+			})
+
+			response := serve(t, w, "GET /fail HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			if !strings.Contains(response, "Content-Type: "+tt.contentType) {
+				t.Errorf("expected content type %s in %q", tt.contentType, response)
+			}
+			if !strings.HasSuffix(response, "\r\n\r\n"+tt.body) {
+				t.Errorf("expected body %s in %q", tt.body, response)
+			}
+		})
+	}
+}
+
+type quotaError struct {
+	Limit int
+}
+
+func (q *quotaError) Error() string {
+	return fmt.Sprintf("quota of %d exceeded", q.Limit)
+}
+
+func TestMapError(t *testing.T) {
+	errNotFound := errors.New("not found")
+
+	w := newTestServer()
+	w.MapError(errNotFound, func(err error) HttpError {
+		return NewHTTPErrorWithMessage(404, "resource not found", BodyTypeJSON)
+	})
+	w.MapError(new(*quotaError), func(err error) HttpError {
+		return NewHTTPErrorWithMessage(429, fmt.Sprintf("limit is %d", err.(*quotaError).Limit), BodyTypeString)
+	})
+
+	var logs strings.Builder
+	w.WithErrorLogger(log.New(&logs, "", 0))
+
+	w.GET("/missing", func(ctx Context) error {
+		return fmt.Errorf("loading user: %w", errNotFound)
+	})
+	w.GET("/quota", func(ctx Context) error {
+		return fmt.Errorf("creating user: %w", &quotaError{Limit: 5})
+	})
+	w.GET("/wrapped", func(ctx Context) error {
+		return fmt.Errorf("validating: %w", NewHTTPErrorWithMessage(400, "invalid name", BodyTypeString))
+	})
+	w.GET("/internal", func(ctx Context) error {
+		return errors.New("connecting to db at 10.0.0.1: refused")
+	})
+
+	tests := []struct {
+		path   string
+		status string
+		body   string
+	}{
+		{path: "/missing", status: "404 Not Found", body: `{"error":"resource not found"}`},
+		{path: "/quota", status: "429 Too Many Requests", body: "limit is 5"},
+		{path: "/wrapped", status: "400 Bad Request", body: "invalid name"},
+		{path: "/internal", status: "500 Internal Server Error", body: "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			response := serve(t, w, "GET "+tt.path+" HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			if !strings.HasPrefix(response, "HTTP/1.1 "+tt.status) {
+				t.Errorf("expected status %s in %q", tt.status, response)
+			}
+			if !strings.HasSuffix(response, "\r\n\r\n"+tt.body) {
+				t.Errorf("expected body %s in %q", tt.body, response)
+			}
+		})
+	}
+
+	if !strings.Contains(logs.String(), "connecting to db at 10.0.0.1: refused") {
+		t.Errorf("expected the unmapped error to be logged, got %q", logs.String())
+	}
+}
+
+func TestMapErrorKeepsOriginalError(t *testing.T) {
+	errConflict := errors.New("conflict")
+
+	w := newTestServer()
+	w.MapError(errConflict, func(err error) HttpError {
+		return NewHttpError(409, BodyTypeJSON)
+	})
+
+	mapped := w.mapError(fmt.Errorf("saving: %w", errConflict))
+	if !errors.Is(mapped, errConflict) {
+		t.Error("expected the mapped error to unwrap to the original error")
+	}
+}
+
+func TestMapErrorInvalidTarget(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected MapError to panic for a target that isn't a pointer to an error type")
+		}
+	}()
+
+	w := newTestServer()
+	w.MapError(new(string), func(err error) HttpError { return NewHttpError(500, BodyTypeJSON) })
+}
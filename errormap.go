@@ -0,0 +1,80 @@
+package whiskey
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrorMapper converts an error returned by a handler into the HttpError sent to the client
+type ErrorMapper func(err error) HttpError
+
+type errorMapping struct {
+	target any
+	mapper ErrorMapper
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// MapError maps errors returned by handlers to an HttpError before they reach the global error handler.
+// target is either an error value, matched with errors.Is, or a pointer to an error type, matched with errors.As.
+// For errors.As targets the mapper receives the matched error, so its fields can be used in the response.
+// Mappings are checked in the order they were registered and the original error stays available through HttpError.Err
+//
+//	w.MapError(ErrNotFound, func(err error) HttpError {
+//		return NewHTTPErrorWithMessage(http.StatusNotFound, "not found", BodyTypeJSON)
+//	})
+//	w.MapError(new(*QuotaError), func(err error) HttpError {
+//		return NewHTTPErrorWithMessage(http.StatusTooManyRequests, err.Error(), BodyTypeJSON)
+//	})
+func (w *Whiskey) MapError(target any, mapper ErrorMapper) {
+	if _, ok := target.(error); !ok {
+		// Same requirements as errors.As, checked up front so a bad mapping fails at startup instead of on a request
+		val := reflect.ValueOf(target)
+		if target == nil || val.Kind() != reflect.Pointer || val.IsNil() {
+			panic("whiskey: MapError target must be an error or a non-nil pointer")
+		}
+		if elem := val.Type().Elem(); elem.Kind() != reflect.Interface && !elem.Implements(errorType) {
+			panic("whiskey: MapError target must point to an interface or a type implementing error")
+		}
+	}
+
+	w.errorMappings = append(w.errorMappings, errorMapping{target: target, mapper: mapper})
+}
+
+// mapError returns the HttpError of the first mapping matching err, or err itself if no mapping matches
+func (w *Whiskey) mapError(err error) error {
+	// Panics aren't domain errors, even if the handler panicked with one
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return err
+	}
+
+	for _, mapping := range w.errorMappings {
+		matched, ok := mapping.match(err)
+		if !ok {
+			continue
+		}
+
+		httpErr := mapping.mapper(matched)
+		if httpErr.Err == nil {
+			httpErr.Err = err
+		}
+		return httpErr
+	}
+
+	return err
+}
+
+func (m errorMapping) match(err error) (error, bool) {
+	if target, ok := m.target.(error); ok {
+		return err, errors.Is(err, target)
+	}
+
+	// Each match gets its own target so concurrent requests don't share it
+	target := reflect.New(reflect.TypeOf(m.target).Elem())
+	if !errors.As(err, target.Interface()) {
+		return nil, false
+	}
+	matched, _ := target.Elem().Interface().(error)
+	return matched, true
+}
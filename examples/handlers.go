@@ -67,7 +67,7 @@ func ApiPathHandler(ctx whiskey.Context) error {
 }
 
 func ProtectedRouteHandler(ctx whiskey.Context) error {
-	userId, hasUserId := ctx.GetString("userId")
+	userId, hasUserId := ctx.GetString(whiskey.AuthUserKey)
 	if !hasUserId {
 		return whiskey.NewHttpError(http.StatusUnauthorized, whiskey.BodyTypeJSON)
 	}
@@ -78,3 +78,16 @@ func ProtectedRouteHandler(ctx whiskey.Context) error {
 	})
 	return nil
 }
+
+type GreetingRequest struct {
+	Name     string `path:"name"`
+	Greeting string `query:"greeting" default:"Hello"`
+}
+
+type GreetingResponse struct {
+	Message string `json:"message" xml:"message"`
+}
+
+func GreetingHandler(ctx whiskey.Context, req GreetingRequest) (GreetingResponse, error) {
+	return GreetingResponse{Message: fmt.Sprintf("%s, %s!", req.Greeting, req.Name)}, nil
+}
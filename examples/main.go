@@ -16,6 +16,8 @@ func main() {
 
 	whiskey.GET("/api/{id}", ApiPathHandler)
 
+	whiskey.GET("/greet/{name}", whiskey2.Typed(GreetingHandler))
+
 	whiskey.GET("/protected", AuthMiddleware, ProtectedRouteHandler)
 
 	// If not paths match, this will get called
@@ -1,9 +1,6 @@
 package main
 
 import (
-	"net/http"
-	"strings"
-
 	"github.com/sriramr98/whiskey"
 )
 
@@ -12,27 +9,10 @@ var (
 	dummyUserId = "k2380sd"
 )
 
-func AuthMiddleware(ctx whiskey.Context) error {
-	UnAuthorizedErr := whiskey.NewHttpError(http.StatusUnauthorized, whiskey.BodyTypeJSON)
-
-	authHeader, hasHeader := ctx.GetHeader("Authorization")
-	if !hasHeader {
-		return UnAuthorizedErr
-	}
-
-	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return UnAuthorizedErr
-	}
-
-	token := strings.Split(authHeader, "Bearer ")[1]
-	if token == "" {
-		return UnAuthorizedErr
-	}
-
-	if token != dummyToken {
-		return UnAuthorizedErr
-	}
-
-	ctx.Set("userId", dummyUserId)
-	return nil
-}
+// AuthMiddleware accepts requests with the header "Authorization: Bearer Abcde" and stores the owner of the token under whiskey.AuthUserKey
+var AuthMiddleware = whiskey.APIKeyAuth(whiskey.APIKeyConfig{
+	Header: whiskey.HeaderAuthorization,
+	Keys: map[string]string{
+		dummyToken: dummyUserId,
+	},
+})
@@ -0,0 +1,118 @@
+package whiskey
+
+import (
+	"net"
+	"time"
+)
+
+// StartEvent is passed to OnStart hooks once the server listens for connections
+type StartEvent struct {
+	Addr net.Addr  // Address the server listens on
+	Time time.Time // When the server started listening
+}
+
+// ShutdownEvent is passed to OnShutdown hooks once Shutdown has finished
+type ShutdownEvent struct {
+	Time     time.Time     // When Shutdown finished
+	Duration time.Duration // How long it took in-flight requests to finish
+	Err      error         // The error returned by Shutdown. It's set if in-flight requests had to be canceled
+}
+
+// ConnEvent is passed to OnConnOpen and OnConnClose hooks
+type ConnEvent struct {
+	RemoteAddr net.Addr
+	LocalAddr  net.Addr
+	Time       time.Time     // When the connection was opened or closed
+	Duration   time.Duration // How long the connection was open. It's only set for OnConnClose
+}
+
+// RequestEvent is passed to OnRequest hooks after the request is routed and before any handler runs
+type RequestEvent struct {
+	Context Context   // Context of the request. Hooks can use it to read the request or store data for the handlers
+	Time    time.Time // When the request was read
+}
+
+// ResponseEvent is passed to OnResponse hooks after the response is written to the connection
+type ResponseEvent struct {
+	Context    Context // Context of the request. It's nil if the request couldn't be read
+	Method     string
+	Path       string
+	Route      string // The route pattern the request matched, e.g. /users/{id}. It's empty if no route matched
+	RemoteAddr string
+	Headers    map[string]string // Headers of the request. It's nil if the request couldn't be read
+	Status     int
+	Bytes      int           // Number of bytes written, including the status line and headers
+	BodyBytes  int           // Size of the response body
+	Start      time.Time     // When the request was read
+	Duration   time.Duration // Time from reading the request until the response was written
+	Err        error         // The error writing the response failed with, if any
+}
+
+// ErrorEvent is passed to OnError hooks when the handler chain returns an error, before the global error handler runs
+type ErrorEvent struct {
+	Context Context
+	Err     error // The error returned by the handlers, before it's mapped with MapError
+	Time    time.Time
+}
+
+// hooks holds the callbacks registered on the engine, run in the order they were added
+type hooks struct {
+	start     []func(StartEvent)
+	shutdown  []func(ShutdownEvent)
+	connOpen  []func(ConnEvent)
+	connClose []func(ConnEvent)
+	request   []func(RequestEvent)
+	response  []func(ResponseEvent)
+	error     []func(ErrorEvent)
+}
+
+// OnStart registers a hook that runs when Run starts listening
+func (w *Whiskey) OnStart(hook func(StartEvent)) {
+	w.hooks.start = append(w.hooks.start, hook)
+}
+
+// OnShutdown registers a hook that runs when Shutdown has finished
+func (w *Whiskey) OnShutdown(hook func(ShutdownEvent)) {
+	w.hooks.shutdown = append(w.hooks.shutdown, hook)
+}
+
+// OnConnOpen registers a hook that runs for every accepted connection before its request is read
+func (w *Whiskey) OnConnOpen(hook func(ConnEvent)) {
+	w.hooks.connOpen = append(w.hooks.connOpen, hook)
+}
+
+// OnConnClose registers a hook that runs after a connection is closed
+func (w *Whiskey) OnConnClose(hook func(ConnEvent)) {
+	w.hooks.connClose = append(w.hooks.connClose, hook)
+}
+
+// OnRequest registers a hook that runs for every routed request before the middlewares and handlers
+func (w *Whiskey) OnRequest(hook func(RequestEvent)) {
+	w.hooks.request = append(w.hooks.request, hook)
+}
+
+// OnResponse registers a hook that runs after a response is written, including responses to requests that couldn't be read or routed.
+// Hooks run on the connection's goroutine before it's released, so slow work should be handed off
+func (w *Whiskey) OnResponse(hook func(ResponseEvent)) {
+	w.hooks.response = append(w.hooks.response, hook)
+}
+
+// OnError registers a hook that runs when the handlers of a request return an error, including recovered panics
+func (w *Whiskey) OnError(hook func(ErrorEvent)) {
+	w.hooks.error = append(w.hooks.error, hook)
+}
+
+// runHooks calls every hook with the event. A panicking hook is logged and doesn't stop the others or the request
+func runHooks[E any](w *Whiskey, name string, hooks []func(E), event E) {
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicErr := newPanicError(p)
+					w.errorLogger.Printf("Recovered from panic in %s hook: %v\n%s", name, panicErr.Value, panicErr.Stack)
+				}
+			}()
+			hook(event)
+		}()
+	}
+}
@@ -0,0 +1,129 @@
+package whiskey
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestHooks(t *testing.T) {
+	var events []string
+	var response ResponseEvent
+	var handlerErr error
+	closed := make(chan ConnEvent, 1)
+
+	w := newTestServer()
+	w.OnConnOpen(func(e ConnEvent) { events = append(events, "open") })
+	w.OnRequest(func(e RequestEvent) {
+		events = append(events, "request "+e.Context.Route())
+		e.Context.Set("user", "bob")
+	})
+	w.OnError(func(e ErrorEvent) {
+		events = append(events, "error")
+		handlerErr = e.Err
+	})
+	w.OnResponse(func(e ResponseEvent) {
+		events = append(events, "response")
+		response = e
+	})
+	w.OnConnClose(func(e ConnEvent) { closed <- e })
+
+	errFailed := errors.New("failed")
+	w.GET("/users/{id}", func(ctx Context) error {
+		user, _ := ctx.GetString("user")
+		ctx.SetHeader("X-User", user)
+		return errFailed
+	})
+
+	raw := serve(t, w, "GET /users/42 HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	conn := <-closed
+
+	expected := []string{"open", "request /users/{id}", "error", "response"}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected hooks %v, got %v", expected, events)
+	}
+	if handlerErr != errFailed {
+		t.Errorf("expected OnError to receive the handler error, got %v", handlerErr)
+	}
+	if !strings.Contains(raw, "X-User: bob") {
+		t.Errorf("expected data stored by OnRequest to reach the handler, got %q", raw)
+	}
+
+	if response.Status != 500 || response.Method != "GET" || response.Path != "/users/42" || response.Route != "/users/{id}" {
+		t.Errorf("unexpected response event %+v", response)
+	}
+	if response.Bytes != len(raw) {
+		t.Errorf("expected %d bytes written, got %d", len(raw), response.Bytes)
+	}
+	if response.Duration <= 0 || response.Start.IsZero() || response.Context == nil {
+		t.Errorf("expected timing and context in the response event, got %+v", response)
+	}
+	if conn.Duration <= 0 {
+		t.Errorf("expected the connection duration to be set, got %v", conn.Duration)
+	}
+}
+
+func TestResponseHookWithoutRoute(t *testing.T) {
+	var response ResponseEvent
+
+	w := newTestServer()
+	w.OnResponse(func(e ResponseEvent) { response = e })
+
+	serve(t, w, "GET /missing HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	if response.Status != 404 || response.Path != "/missing" || response.Route != "" || response.Context == nil {
+		t.Errorf("unexpected response event %+v", response)
+	}
+}
+
+func TestPanickingHookDoesNotBreakRequest(t *testing.T) {
+	w := newTestServer()
+	w.OnRequest(func(e RequestEvent) { panic("broken hook") })
+	w.GET("/hello", func(ctx Context) error {
+		return ctx.String(200, "hello")
+	})
+
+	response := serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 200 OK") {
+		t.Fatalf("unexpected response %q", response)
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	started := make(chan StartEvent, 1)
+	var shutdown ShutdownEvent
+
+	w := newTestServer()
+	w.config.Addr = "127.0.0.1"
+	w.config.Port = 0
+	w.OnStart(func(e StartEvent) { started <- e })
+	w.OnShutdown(func(e ShutdownEvent) { shutdown = e })
+
+	stopped := make(chan struct{})
+	go func() {
+		w.Run()
+		close(stopped)
+	}()
+
+	var start StartEvent
+	select {
+	case start = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStart hook didn't run")
+	}
+	if _, ok := start.Addr.(*net.TCPAddr); !ok {
+		t.Errorf("expected the listening address, got %v", start.Addr)
+	}
+
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	<-stopped
+
+	if shutdown.Time.IsZero() || shutdown.Err != nil {
+		t.Errorf("unexpected shutdown event %+v", shutdown)
+	}
+}
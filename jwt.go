@@ -0,0 +1,377 @@
+package whiskey
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWTClaimsKey is the DataStore key holding the Claims of a verified JWT. Read them with JWTClaims
+var JWTClaimsKey string = "jwt_claims"
+
+// Claims are the claims of a verified JWT
+type Claims map[string]any
+
+// Subject returns the sub claim
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Issuer returns the iss claim
+func (c Claims) Issuer() string {
+	iss, _ := c["iss"].(string)
+	return iss
+}
+
+// Audience returns the aud claim, which may be a single string or a list
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []any:
+		audience := make([]string, 0, len(aud))
+		for _, value := range aud {
+			if s, ok := value.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+		return audience
+	}
+	return nil
+}
+
+// time returns a NumericDate claim like exp, nbf or iat
+func (c Claims) time(name string) (time.Time, bool, error) {
+	value, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: %s isn't a number", ErrMalformedToken, name)
+	}
+	whole, frac := int64(seconds), seconds-float64(int64(seconds))
+	return time.Unix(whole, int64(frac*float64(time.Second))), true, nil
+}
+
+// JWTClaims returns the claims stored by the JWT middleware
+func JWTClaims(ctx Context) (Claims, bool) {
+	value, ok := ctx.Get(JWTClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(Claims)
+	return claims, ok
+}
+
+// JWTConfig configures the JWT middleware. At least one of Secret, PublicKey or KeySet is required
+type JWTConfig struct {
+	Secret    []byte           // Verifies HS256 tokens
+	PublicKey crypto.PublicKey // Verifies RS256 tokens if it's an *rsa.PublicKey or ES256 tokens if it's a P-256 *ecdsa.PublicKey
+	KeySet    *JWKS            // Verifies tokens by their kid header. Tokens without a kid fall back to Secret and PublicKey
+
+	Issuer   string        // Required value of the iss claim. Not checked if empty
+	Audience string        // Value the aud claim must contain. Not checked if empty
+	Leeway   time.Duration // Clock skew tolerated when checking exp and nbf
+
+	now func() time.Time
+}
+
+// JWT returns a middleware that verifies the Bearer token of every request. Tokens must be signed with HS256, RS256 or ES256 using a key that matches the algorithm,
+// and their exp, nbf, iss and aud claims are checked. The claims are stored under JWTClaimsKey and the subject under AuthUserKey.
+// Invalid tokens get a 401 HttpError with a WWW-Authenticate challenge through the global error handler. The HttpError wraps the reason, e.g. ErrTokenExpired
+func JWT(config JWTConfig) HttpHandler {
+	if len(config.Secret) == 0 && config.PublicKey == nil && config.KeySet == nil {
+		panic("whiskey: JWT middleware requires a secret, public key or key set")
+	}
+	if config.now == nil {
+		config.now = time.Now
+	}
+
+	return func(ctx Context) error {
+		token, ok := authorizationCredentials(ctx, "Bearer")
+		if !ok || token == "" {
+			ctx.SetHeader(HeaderWWWAuthenticate, "Bearer")
+			return unauthorizedError("missing token", ErrMissingCredentials)
+		}
+
+		claims, err := config.verify(token)
+		if err != nil {
+			ctx.SetHeader(HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return unauthorizedError("invalid token", err)
+		}
+
+		ctx.Set(JWTClaimsKey, claims)
+		if sub := claims.Subject(); sub != "" {
+			ctx.Set(AuthUserKey, sub)
+		}
+		return nil
+	}
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// verify checks the signature and claims of a compact serialized JWT
+func (config JWTConfig) verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	key, err := config.key(header)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := config.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// key returns the key a token with the given header must be signed with
+func (config JWTConfig) key(header jwtHeader) (any, error) {
+	if header.KeyID != "" && config.KeySet != nil {
+		key, ok := config.KeySet.Key(header.KeyID)
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if alg, restricted := config.KeySet.algorithms[header.KeyID]; restricted && alg != header.Algorithm {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		return key, nil
+	}
+
+	// The algorithm of the token picks between the configured keys, verifyJWTSignature then makes sure the key type matches it
+	switch header.Algorithm {
+	case "HS256":
+		if len(config.Secret) > 0 {
+			return config.Secret, nil
+		}
+	case "RS256", "ES256":
+		if config.PublicKey != nil {
+			return config.PublicKey, nil
+		}
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return nil, ErrUnknownKey
+}
+
+func verifyJWTSignature(algorithm string, key any, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch algorithm {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+	case "RS256":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+	case "ES256":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || publicKey.Curve != elliptic.P256() {
+			return ErrUnsupportedAlgorithm
+		}
+		// JWS signatures are the 32 byte r and s values concatenated, not ASN.1
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	default:
+		// "none" and every other algorithm are rejected
+		return ErrUnsupportedAlgorithm
+	}
+
+	return nil
+}
+
+func (config JWTConfig) validateClaims(claims Claims) error {
+	now := config.now()
+
+	expiresAt, ok, err := claims.time("exp")
+	if err != nil {
+		return err
+	}
+	if ok && !now.Before(expiresAt.Add(config.Leeway)) {
+		return ErrTokenExpired
+	}
+
+	notBefore, ok, err := claims.time("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(config.Leeway).Before(notBefore) {
+		return ErrTokenNotYetValid
+	}
+
+	if config.Issuer != "" && claims.Issuer() != config.Issuer {
+		return ErrInvalidIssuer
+	}
+	if config.Audience != "" && !slices.Contains(claims.Audience(), config.Audience) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(decoded, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// JWKS is a set of keys in the JSON Web Key Set format, looked up by their kid
+type JWKS struct {
+	keys       map[string]any
+	algorithms map[string]string // The alg of keys that restrict which algorithm they're used with
+}
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	K         string `json:"k"`
+}
+
+// LoadJWKS reads a JSON Web Key Set from a file. RSA, P-256 EC and symmetric (oct) keys are supported, keys without a kid or only meant for encryption are skipped
+func LoadJWKS(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS parses a JSON Web Key Set
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	jwks := &JWKS{keys: make(map[string]any), algorithms: make(map[string]string)}
+	for _, key := range set.Keys {
+		if key.KeyID == "" || key.Use == "enc" {
+			continue
+		}
+
+		parsed, err := key.parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %w", key.KeyID, err)
+		}
+		jwks.keys[key.KeyID] = parsed
+		if key.Algorithm != "" {
+			jwks.algorithms[key.KeyID] = key.Algorithm
+		}
+	}
+
+	return jwks, nil
+}
+
+// Key returns the key with the given kid
+func (j *JWKS) Key(keyID string) (any, bool) {
+	key, ok := j.keys[keyID]
+	return key, ok
+}
+
+func (k jwk) parse() (any, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		exponent := int(new(big.Int).SetBytes(e).Int64())
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid coordinates")
+		}
+		// Parsing the point with crypto/ecdh rejects points that aren't on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid point: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("invalid secret")
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
@@ -0,0 +1,228 @@
+package whiskey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var jwtTestNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// signJWT builds a compact JWT signed with key, which is a []byte secret, an *rsa.PrivateKey or an *ecdsa.PrivateKey depending on alg
+func signJWT(t *testing.T, alg string, kid string, key any, claims Claims) string {
+	t.Helper()
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case "RS256":
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerify(t *testing.T) {
+	secret := []byte("hmac-secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	valid := Claims{
+		"sub": "user-1",
+		"iss": "https://auth.example.com",
+		"aud": []string{"api", "web"},
+		"exp": float64(jwtTestNow.Add(time.Hour).Unix()),
+		"nbf": float64(jwtTestNow.Add(-time.Minute).Unix()),
+	}
+	with := func(key string, value any) Claims {
+		claims := Claims{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	hsConfig := JWTConfig{Secret: secret, Issuer: "https://auth.example.com", Audience: "api", now: func() time.Time { return jwtTestNow }}
+	rsConfig := JWTConfig{PublicKey: &rsaKey.PublicKey, now: hsConfig.now}
+	esConfig := JWTConfig{PublicKey: &ecKey.PublicKey, now: hsConfig.now}
+
+	tests := []struct {
+		name   string
+		config JWTConfig
+		token  string
+		err    error
+	}{
+		{name: "HS256", config: hsConfig, token: signJWT(t, "HS256", "", secret, valid)},
+		{name: "RS256", config: rsConfig, token: signJWT(t, "RS256", "", rsaKey, valid)},
+		{name: "ES256", config: esConfig, token: signJWT(t, "ES256", "", ecKey, valid)},
+		{name: "wrong secret", config: hsConfig, token: signJWT(t, "HS256", "", []byte("other"), valid), err: ErrInvalidSignature},
+		{name: "tampered claims", config: hsConfig, token: tamper(signJWT(t, "HS256", "", secret, valid)), err: ErrInvalidSignature},
+		{name: "expired", config: hsConfig, token: signJWT(t, "HS256", "", secret, with("exp", float64(jwtTestNow.Add(-time.Second).Unix()))), err: ErrTokenExpired},
+		{name: "not yet valid", config: hsConfig, token: signJWT(t, "HS256", "", secret, with("nbf", float64(jwtTestNow.Add(time.Hour).Unix()))), err: ErrTokenNotYetValid},
+		{name: "wrong issuer", config: hsConfig, token: signJWT(t, "HS256", "", secret, with("iss", "https://evil.com")), err: ErrInvalidIssuer},
+		{name: "wrong audience", config: hsConfig, token: signJWT(t, "HS256", "", secret, with("aud", "admin")), err: ErrInvalidAudience},
+		{name: "none algorithm", config: hsConfig, token: noneToken(valid), err: ErrUnsupportedAlgorithm},
+		{name: "HS256 with only a public key", config: rsConfig, token: signJWT(t, "HS256", "", secret, valid), err: ErrUnknownKey},
+		{name: "malformed", config: hsConfig, token: "not.a-token", err: ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.config.verify(tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if tt.err == nil && claims.Subject() != "user-1" {
+				t.Errorf("unexpected claims %v", claims)
+			}
+		})
+	}
+}
+
+func TestJWTLeeway(t *testing.T) {
+	secret := []byte("hmac-secret")
+	config := JWTConfig{Secret: secret, Leeway: time.Minute, now: func() time.Time { return jwtTestNow }}
+	token := signJWT(t, "HS256", "", secret, Claims{"exp": float64(jwtTestNow.Add(-30 * time.Second).Unix())})
+
+	if _, err := config.verify(token); err != nil {
+		t.Errorf("expected the leeway to accept a recently expired token, got %v", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "n": %q, "e": %q},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": %q, "y": %q},
+		{"kty": "oct", "kid": "hmac-1", "k": %q},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "", "e": ""}
+	]}`,
+		encode(rsaKey.N.Bytes()), encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+		encode(ecKey.X.FillBytes(make([]byte, 32))), encode(ecKey.Y.FillBytes(make([]byte, 32))),
+		encode([]byte("hmac-secret")),
+	)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(jwks), 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+
+	keySet, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("failed to load JWKS: %v", err)
+	}
+	config := JWTConfig{KeySet: keySet, now: func() time.Time { return jwtTestNow }}
+	claims := Claims{"sub": "user-1"}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "RSA key", token: signJWT(t, "RS256", "rsa-1", rsaKey, claims)},
+		{name: "EC key", token: signJWT(t, "ES256", "ec-1", ecKey, claims)},
+		{name: "symmetric key", token: signJWT(t, "HS256", "hmac-1", []byte("hmac-secret"), claims)},
+		{name: "unknown kid", token: signJWT(t, "RS256", "rsa-2", rsaKey, claims), err: ErrUnknownKey},
+		{name: "algorithm not matching the key", token: signJWT(t, "HS256", "rsa-1", []byte("hmac-secret"), claims), err: ErrUnsupportedAlgorithm},
+		{name: "key type not matching the algorithm", token: signJWT(t, "HS256", "ec-1", []byte("hmac-secret"), claims), err: ErrUnsupportedAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := config.verify(tt.token); !errors.Is(err, tt.err) {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	if _, ok := keySet.Key("enc-1"); ok {
+		t.Error("expected encryption keys to be skipped")
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	secret := []byte("hmac-secret")
+
+	w := newTestServer()
+	w.MapError(ErrTokenExpired, func(err error) HttpError {
+		return NewHTTPErrorWithMessage(401, "token expired", BodyTypeJSON)
+	})
+	w.Use(JWT(JWTConfig{Secret: secret}))
+	w.GET("/me", func(ctx Context) error {
+		claims, ok := JWTClaims(ctx)
+		if !ok {
+			return ctx.String(500, "missing claims")
+		}
+		user, _ := ctx.GetString(AuthUserKey)
+		return ctx.String(200, user+" "+claims["role"].(string))
+	})
+
+	token := signJWT(t, "HS256", "", secret, Claims{"sub": "user-1", "role": "admin", "exp": float64(time.Now().Add(time.Hour).Unix())})
+	response := serve(t, w, "GET /me HTTP/1.1\r\nAuthorization: Bearer "+token+"\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 200 OK") || !strings.HasSuffix(response, "user-1 admin") {
+		t.Errorf("expected the claims on the context, got %q", response)
+	}
+
+	response = serve(t, w, "GET /me HTTP/1.1\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 401") || !strings.Contains(response, "WWW-Authenticate: Bearer\r\n") {
+		t.Errorf("expected a challenge for a missing token, got %q", response)
+	}
+
+	expired := signJWT(t, "HS256", "", secret, Claims{"sub": "user-1", "exp": float64(time.Now().Add(-time.Hour).Unix())})
+	response = serve(t, w, "GET /me HTTP/1.1\r\nAuthorization: Bearer "+expired+"\r\n\r\n")
+	if !strings.Contains(response, `WWW-Authenticate: Bearer error="invalid_token"`) || !strings.HasSuffix(response, `{"error":"token expired"}`) {
+		t.Errorf("expected the failure to go through the error handler and mappings, got %q", response)
+	}
+}
+
+// tamper replaces the claims of a token while keeping its signature
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`))
+	return strings.Join(parts, ".")
+}
+
+func noneToken(claims Claims) string {
+	claimsJSON, _ := json.Marshal(claims)
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON) + "."
+}
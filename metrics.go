@@ -0,0 +1,219 @@
+package whiskey
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content types of the exposition formats, with the versions Metrics renders
+var (
+	prometheusTextContentType = MimeTypeText + "; version=0.0.4; charset=utf-8"
+	openMetricsContentType    = MimeTypeOpenMetrics + "; version=1.0.0; charset=utf-8"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the request latency histogram, the same as the Prometheus client defaults
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// MetricsConfig configures the metrics recorded by Whiskey.Metrics
+type MetricsConfig struct {
+	Namespace string    // Prefix of every metric name. Defaults to "whiskey"
+	Buckets   []float64 // Upper bounds in seconds of the request latency histogram, in increasing order. Defaults to DefaultLatencyBuckets
+}
+
+// Metrics holds request and connection metrics of a server. Serve them by mounting Handler, e.g. w.GET("/metrics", metrics.Handler())
+type Metrics struct {
+	config MetricsConfig
+
+	mu          sync.Mutex
+	requests    map[requestSeries]*requestStats
+	inFlight    map[inFlightSeries]int64
+	connections int64 // Connections accepted since the server started
+	openConns   int64 // Connections currently open
+}
+
+// requestSeries identifies the labels of the request counter and latency histogram. The route pattern is used instead of the path so the number of series stays bounded
+type requestSeries struct {
+	method string
+	route  string
+	status int
+}
+
+type inFlightSeries struct {
+	method string
+	route  string
+}
+
+type requestStats struct {
+	count   uint64
+	sum     float64  // Total latency in seconds
+	buckets []uint64 // Number of requests per bucket. They are made cumulative when rendered
+}
+
+// Metrics starts recording RED metrics for every request, labeled by method, route pattern and status, and connection stats.
+// The returned Metrics renders them in the Prometheus text format or OpenMetrics
+func (w *Whiskey) Metrics(config MetricsConfig) *Metrics {
+	if config.Namespace == "" {
+		config.Namespace = "whiskey"
+	}
+	if len(config.Buckets) == 0 {
+		config.Buckets = DefaultLatencyBuckets
+	}
+
+	m := &Metrics{
+		config:   config,
+		requests: make(map[requestSeries]*requestStats),
+		inFlight: make(map[inFlightSeries]int64),
+	}
+
+	w.OnConnOpen(func(ConnEvent) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.connections++
+		m.openConns++
+	})
+	w.OnConnClose(func(ConnEvent) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.openConns--
+	})
+	w.OnRequest(func(e RequestEvent) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inFlight[inFlightSeries{method: e.Context.Method(), route: e.Context.Route()}]++
+	})
+	w.OnResponse(m.observe)
+
+	return m
+}
+
+func (m *Metrics) observe(e ResponseEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Only routed requests were counted as in flight by the OnRequest hook
+	if e.Context != nil {
+		m.inFlight[inFlightSeries{method: e.Method, route: e.Route}]--
+	}
+
+	series := requestSeries{method: e.Method, route: e.Route, status: e.Status}
+	stats, ok := m.requests[series]
+	if !ok {
+		stats = &requestStats{buckets: make([]uint64, len(m.config.Buckets))}
+		m.requests[series] = stats
+	}
+
+	seconds := e.Duration.Seconds()
+	stats.count++
+	stats.sum += seconds
+	if idx, _ := slices.BinarySearch(m.config.Buckets, seconds); idx < len(stats.buckets) {
+		stats.buckets[idx]++
+	}
+}
+
+// Handler returns a handler serving the metrics. Clients asking for application/openmetrics-text in their Accept header get OpenMetrics, others the Prometheus text format
+func (m *Metrics) Handler() HttpHandler {
+	return func(ctx Context) error {
+		accept, _ := ctx.GetHeader(HeaderAccept)
+		if format, _ := negotiate(accept, []string{MimeTypeText, MimeTypeOpenMetrics}); format == MimeTypeOpenMetrics {
+			return ctx.Bytes(http.StatusOK, openMetricsContentType, []byte(m.render(true)))
+		}
+		return ctx.Bytes(http.StatusOK, prometheusTextContentType, []byte(m.render(false)))
+	}
+}
+
+// render writes every metric in the Prometheus text exposition format, or OpenMetrics if openMetrics is set
+func (m *Metrics) render(openMetrics bool) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	ns := m.config.Namespace
+
+	requestSeries := slices.SortedFunc(maps.Keys(m.requests), func(a, b requestSeries) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method), cmp.Compare(a.status, b.status))
+	})
+
+	writeHeader(&b, ns+"_http_requests", "counter", "Total number of HTTP requests handled.", openMetrics)
+	for _, series := range requestSeries {
+		fmt.Fprintf(&b, "%s_http_requests_total%s %d\n", ns, series.labels(), m.requests[series].count)
+	}
+
+	writeHeader(&b, ns+"_http_request_duration_seconds", "histogram", "Time from reading an HTTP request until its response was written.", openMetrics)
+	for _, series := range requestSeries {
+		stats := m.requests[series]
+		labels := series.labelPairs()
+
+		var cumulative uint64
+		for idx, bound := range m.config.Buckets {
+			cumulative += stats.buckets[idx]
+			fmt.Fprintf(&b, "%s_http_request_duration_seconds_bucket%s %d\n", ns, formatLabels(append(labels, "le", formatFloat(bound))), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_http_request_duration_seconds_bucket%s %d\n", ns, formatLabels(append(labels, "le", "+Inf")), stats.count)
+		fmt.Fprintf(&b, "%s_http_request_duration_seconds_sum%s %s\n", ns, formatLabels(labels), formatFloat(stats.sum))
+		fmt.Fprintf(&b, "%s_http_request_duration_seconds_count%s %d\n", ns, formatLabels(labels), stats.count)
+	}
+
+	inFlightSeries := slices.SortedFunc(maps.Keys(m.inFlight), func(a, b inFlightSeries) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method))
+	})
+
+	writeHeader(&b, ns+"_http_requests_in_flight", "gauge", "Number of HTTP requests being handled.", openMetrics)
+	for _, series := range inFlightSeries {
+		fmt.Fprintf(&b, "%s_http_requests_in_flight%s %d\n", ns, formatLabels([]string{"method", series.method, "route", series.route}), m.inFlight[series])
+	}
+
+	writeHeader(&b, ns+"_connections", "counter", "Total number of connections accepted.", openMetrics)
+	fmt.Fprintf(&b, "%s_connections_total %d\n", ns, m.connections)
+
+	writeHeader(&b, ns+"_connections_open", "gauge", "Number of connections currently open.", openMetrics)
+	fmt.Fprintf(&b, "%s_connections_open %d\n", ns, m.openConns)
+
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+
+	return b.String()
+}
+
+func (s requestSeries) labelPairs() []string {
+	return []string{"method", s.method, "route", s.route, "status", strconv.Itoa(s.status)}
+}
+
+func (s requestSeries) labels() string {
+	return formatLabels(s.labelPairs())
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family. OpenMetrics names counter families without the _total suffix their samples have, the Prometheus text format names them with it
+func writeHeader(b *strings.Builder, name string, metricType string, help string, openMetrics bool) {
+	if metricType == "counter" && !openMetrics {
+		name += "_total"
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, metricType)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels formats name/value pairs as {name="value",...}
+func formatLabels(pairs []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for idx := 0; idx+1 < len(pairs); idx += 2 {
+		if idx > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, pairs[idx], labelValueEscaper.Replace(pairs[idx+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,69 @@
+package whiskey
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	w := newTestServer()
+	metrics := w.Metrics(MetricsConfig{Namespace: "app", Buckets: []float64{0.5, 1}})
+	w.GET("/metrics", metrics.Handler())
+	w.GET("/users/{id}", func(ctx Context) error {
+		return ctx.String(200, "user")
+	})
+
+	serve(t, w, "GET /users/1 HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	serve(t, w, "GET /users/2 HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	serve(t, w, "GET /missing HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	response := serve(t, w, "GET /metrics HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.Contains(response, "Content-Type: text/plain; version=0.0.4; charset=utf-8") {
+		t.Errorf("expected the Prometheus text format, got %q", response)
+	}
+
+	expected := []string{
+		"# TYPE app_http_requests_total counter",
+		`app_http_requests_total{method="GET",route="/users/{id}",status="200"} 2`,
+		`app_http_requests_total{method="GET",route="",status="404"} 1`,
+		"# TYPE app_http_request_duration_seconds histogram",
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="1"} 2`,
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="+Inf"} 2`,
+		`app_http_request_duration_seconds_count{method="GET",route="/users/{id}",status="200"} 2`,
+		"# TYPE app_http_requests_in_flight gauge",
+		`app_http_requests_in_flight{method="GET",route="/users/{id}"} 0`,
+		`app_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+		"app_connections_total 4",
+	}
+	for _, line := range expected {
+		if !strings.Contains(response, line+"\n") {
+			t.Errorf("expected %q in %s", line, response)
+		}
+	}
+	if strings.Contains(response, "/users/1") || strings.Contains(response, "# EOF") {
+		t.Errorf("expected route patterns and no EOF marker, got %s", response)
+	}
+}
+
+func TestMetricsOpenMetrics(t *testing.T) {
+	w := newTestServer()
+	metrics := w.Metrics(MetricsConfig{})
+	w.GET("/metrics", metrics.Handler())
+
+	accept := "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5,*/*;q=0.1"
+	response := serve(t, w, "GET /metrics HTTP/1.1\r\nHost: localhost\r\nAccept: "+accept+"\r\n\r\n")
+
+	if !strings.Contains(response, "Content-Type: application/openmetrics-text; version=1.0.0; charset=utf-8") {
+		t.Errorf("expected OpenMetrics, got %q", response)
+	}
+	if !strings.Contains(response, "# TYPE whiskey_http_requests counter\n") || !strings.HasSuffix(response, "# EOF\n") {
+		t.Errorf("expected OpenMetrics counter names and EOF marker, got %s", response)
+	}
+}
+
+func TestFormatLabelsEscapesValues(t *testing.T) {
+	labels := formatLabels([]string{"route", "a\"b\\c\nd"})
+	if labels != `{route="a\"b\\c\nd"}` {
+		t.Errorf("unexpected labels %s", labels)
+	}
+}
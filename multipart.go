@@ -0,0 +1,159 @@
+package whiskey
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+)
+
+// MultipartForm holds a parsed multipart/form-data body. Text fields are kept in Value and file parts in File
+type MultipartForm struct {
+	Value map[string][]string
+	File  map[string][]*FormFile
+}
+
+// FormFile describes a single uploaded file. Small files are kept in memory, larger files are spooled to a temporary file on disk
+type FormFile struct {
+	Filename string
+	Header   textproto.MIMEHeader
+	Size     int64
+
+	content []byte
+	tmpPath string
+}
+
+// Open returns a reader for the file contents. The caller is responsible for closing it
+func (f *FormFile) Open() (multipart.File, error) {
+	if f.tmpPath != "" {
+		return os.Open(f.tmpPath)
+	}
+	return memoryFile{bytes.NewReader(f.content)}, nil
+}
+
+// memoryFile adapts an in memory file to the multipart.File interface
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+// RemoveAll deletes every temporary file created while parsing the form
+func (m *MultipartForm) RemoveAll() error {
+	var errs []error
+	for _, files := range m.File {
+		for _, file := range files {
+			if file.tmpPath == "" {
+				continue
+			}
+			if err := os.Remove(file.tmpPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readMultipartForm consumes every part of the reader. At most maxMemory bytes of file content are kept in memory across all files,
+// anything above is written to temporary files. maxParts limits the number of parts, 0 disables the limit
+func readMultipartForm(reader *multipart.Reader, maxMemory int64, maxParts int) (*MultipartForm, error) {
+	form := &MultipartForm{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*FormFile),
+	}
+
+	malformedErr := NewHTTPErrorWithMessage(http.StatusBadRequest, "malformed multipart body", BodyTypeJSON)
+	memoryLeft := max(maxMemory, 0)
+	parts := 0
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			form.RemoveAll()
+			return nil, malformedErr
+		}
+
+		parts++
+		if maxParts > 0 && parts > maxParts {
+			part.Close()
+			form.RemoveAll()
+			return nil, NewHTTPErrorWithMessage(http.StatusRequestEntityTooLarge, "too many parts in multipart body", BodyTypeJSON)
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+
+		filename := part.FileName()
+		if filename == "" {
+			// Parts without a filename are plain text fields
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, part); err != nil {
+				part.Close()
+				form.RemoveAll()
+				return nil, malformedErr
+			}
+			part.Close()
+			form.Value[name] = append(form.Value[name], buf.String())
+			continue
+		}
+
+		file, err := readFormFile(part, filename, &memoryLeft)
+		part.Close()
+		if err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+		form.File[name] = append(form.File[name], file)
+	}
+
+	return form, nil
+}
+
+// readFormFile reads a file part into memory if it fits in what's left of the memory budget, otherwise it spools it to a temporary file
+func readFormFile(part *multipart.Part, filename string, memoryLeft *int64) (*FormFile, error) {
+	file := &FormFile{
+		Filename: filename,
+		Header:   part.Header,
+	}
+
+	var buf bytes.Buffer
+	// Reading one byte more than the budget tells us whether the file fits in memory
+	n, err := io.CopyN(&buf, part, *memoryLeft+1)
+	if err != nil && err != io.EOF {
+		return nil, NewHTTPErrorWithMessage(http.StatusBadRequest, "malformed multipart body", BodyTypeJSON)
+	}
+
+	if n <= *memoryLeft {
+		file.content = buf.Bytes()
+		file.Size = n
+		*memoryLeft -= n
+		return file, nil
+	}
+
+	tmp, err := os.CreateTemp("", "whiskey-multipart-")
+	if err != nil {
+		return nil, err
+	}
+	defer tmp.Close()
+
+	file.tmpPath = tmp.Name()
+	size, err := io.Copy(tmp, io.MultiReader(&buf, part))
+	if err != nil {
+		os.Remove(file.tmpPath)
+		return nil, err
+	}
+	file.Size = size
+
+	return file, nil
+}
@@ -0,0 +1,245 @@
+package whiskey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMultipartContext(t *testing.T, config ServerConfig, fields map[string]string, files map[string]string) *RequestContext {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatalf("failed to write field: %v", err)
+		}
+	}
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatalf("failed to create file part: %v", err)
+		}
+		part.Write([]byte(content))
+	}
+	writer.Close()
+
+	engine := New()
+	engine.WithConfig(config)
+	return &RequestContext{
+		request: HttpRequest{
+			headers: map[string]string{HeaderContentType: writer.FormDataContentType()},
+			body:    body.Bytes(),
+		},
+		engine: &engine,
+	}
+}
+
+func TestMultipartForm(t *testing.T) {
+	config := defaultConfig
+	config.MaxMultipartMemory = 10
+
+	ctx := newMultipartContext(t, config,
+		map[string]string{"name": "John", "age": "30"},
+		map[string]string{"small": "tiny", "large": strings.Repeat("x", 100)},
+	)
+
+	type FormType struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	var form FormType
+	if err := ctx.BindForm(&form); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if form.Name != "John" || form.Age != 30 {
+		t.Fatalf("expected form {John 30}, got %+v", form)
+	}
+
+	small, err := ctx.FormFile("small")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if small.tmpPath != "" || small.Size != 4 {
+		t.Fatalf("expected small file to be kept in memory, got %+v", small)
+	}
+
+	large, err := ctx.FormFile("large")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if large.tmpPath == "" || large.Size != 100 {
+		t.Fatalf("expected large file to be spooled to disk, got %+v", large)
+	}
+
+	f, err := large.Open()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	content, _ := io.ReadAll(f)
+	f.Close()
+	if string(content) != strings.Repeat("x", 100) {
+		t.Fatalf("unexpected content for large file %q", content)
+	}
+
+	if _, err := ctx.FormFile("missing"); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+
+	if err := ctx.release(); err != nil {
+		t.Fatalf("expected no error on release, got %v", err)
+	}
+	if _, err := os.Stat(large.tmpPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected temporary file to be removed, got %v", err)
+	}
+}
+
+func TestMultipartFormLimits(t *testing.T) {
+	config := defaultConfig
+	config.MaxMultipartParts = 2
+
+	ctx := newMultipartContext(t, config, map[string]string{"a": "1", "b": "2", "c": "3"}, nil)
+	_, err := ctx.MultipartForm()
+
+	var httpErr HttpError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 error, got %v", err)
+	}
+}
+
+func TestMultipartReader(t *testing.T) {
+	ctx := newMultipartContext(t, defaultConfig, map[string]string{"name": "John"}, nil)
+
+	reader, err := ctx.MultipartReader()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	part, err := reader.NextPart()
+	if err != nil || part.FormName() != "name" {
+		t.Fatalf("expected part name, got %v %v", part, err)
+	}
+
+	ctx.request.headers[HeaderContentType] = MimeTypeMultipartForm
+	if _, err := ctx.MultipartReader(); err == nil {
+		t.Fatalf("expected error for missing boundary")
+	}
+
+	ctx.request.headers[HeaderContentType] = MimeTypeJSON
+	_, err = ctx.MultipartReader()
+	var httpErr HttpError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 error, got %v", err)
+	}
+}
+
+func TestTimeoutHandsMultipartFormToLateHandlers(t *testing.T) {
+	config := defaultConfig
+	config.MaxMultipartMemory = 1
+
+	logs := make(logWriter, 1)
+	ctx := newMultipartContext(t, config, nil, map[string]string{"upload": "content"})
+	ctx.engine.WithErrorLogger(log.New(logs, "", 0))
+	ctx.response = &HttpResponse{headers: make(map[string]string)}
+
+	// Parsed before the Timeout middleware, the form is shared with the late handler
+	if _, err := ctx.MultipartForm(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	timedOut := make(chan string)
+	finish := make(chan struct{})
+	ctx.handlers = []HttpHandler{Timeout(10 * time.Millisecond), func(ctx Context) error {
+		<-ctx.Context().Done()
+		file, _ := ctx.FormFile("upload")
+		timedOut <- file.tmpPath
+		<-finish
+		panic("broke after timeout")
+	}}
+
+	go func() {
+		err := ctx.Next()
+		if err == nil || !strings.Contains(err.Error(), "request timed out") {
+			t.Errorf("expected timeout error, got %v", err)
+		}
+		// The request is done, the late handler must still be able to read its upload
+		if err := ctx.release(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+
+	tmpPath := <-timedOut
+	time.Sleep(20 * time.Millisecond)
+	if _, err := os.Stat(tmpPath); err != nil {
+		t.Fatalf("expected upload to be kept while the handler runs, got %v", err)
+	}
+
+	close(finish)
+	if logged := <-logs; !strings.Contains(logged, "Recovered from panic after timeout: broke after timeout") {
+		t.Fatalf("expected late panic to be logged, got %q", logged)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := os.Stat(tmpPath)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected upload to be removed once the handler finished, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// logWriter hands every line written by a logger to the test goroutine
+type logWriter chan string
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestMultipartUploadIsStreamed(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("name", "report")
+	part, _ := writer.CreateFormFile("upload", "report.txt")
+	part.Write([]byte(strings.Repeat("x", 5000)))
+	writer.Close()
+
+	config := defaultConfig
+	config.MaxMultipartMemory = 1024
+
+	w := newTestServer()
+	w.WithConfig(config)
+	w.POST("/upload", func(ctx Context) error {
+		if len(ctx.Body()) != 0 {
+			return ctx.String(500, "body was buffered")
+		}
+		name, _ := ctx.FormValue("name")
+		file, err := ctx.FormFile("upload")
+		if err != nil {
+			return err
+		}
+		if file.tmpPath == "" {
+			return ctx.String(500, "upload was kept in memory")
+		}
+		return ctx.String(200, fmt.Sprintf("%s %d", name, file.Size))
+	})
+
+	response := serve(t, w, "POST /upload HTTP/1.1\r\nHost: localhost\r\n"+
+		"Content-Type: "+writer.FormDataContentType()+"\r\n"+
+		"Content-Length: "+strconv.Itoa(body.Len())+"\r\n\r\n"+body.String())
+	if !strings.HasPrefix(response, "HTTP/1.1 200 OK") || !strings.HasSuffix(response, "report 5000") {
+		t.Fatalf("expected the upload to be streamed to a temporary file, got %q", response)
+	}
+}
@@ -0,0 +1,97 @@
+package whiskey
+
+import (
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// acceptRange is a single media range of an Accept header like `text/*;q=0.8`
+type acceptRange struct {
+	mainType string
+	subType  string
+	quality  float64
+}
+
+// specificity ranks how precise a range is. An exact type beats `type/*` which beats `*/*`
+func (a acceptRange) specificity() int {
+	switch {
+	case a.mainType == "*":
+		return 0
+	case a.subType == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (a acceptRange) matches(mediaType string) bool {
+	mainType, subType, _ := strings.Cut(mediaType, "/")
+	return (a.mainType == "*" || a.mainType == mainType) && (a.subType == "*" || a.subType == subType)
+}
+
+// parseAccept parses an Accept header into its media ranges. Invalid ranges are skipped and a missing q parameter means a quality of 1
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for part := range strings.SplitSeq(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		mainType, subType, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+
+		ranges = append(ranges, acceptRange{mainType: mainType, subType: subType, quality: quality})
+	}
+	return ranges
+}
+
+// negotiate picks the offered media type the client prefers according to the Accept header.
+// Every offer is weighted by the most specific range matching it, ties go to the offer matched more specifically and then to the earlier offer.
+// An empty Accept header accepts the first offer. The boolean is false when the client accepts none of the offers
+func negotiate(accept string, offers []string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+
+	ranges := parseAccept(accept)
+	// Most specific ranges first so the first match for an offer is the one that decides its quality
+	slices.SortStableFunc(ranges, func(a, b acceptRange) int {
+		return b.specificity() - a.specificity()
+	})
+
+	best, bestQuality, bestSpecificity := "", 0.0, -1
+	for _, offer := range offers {
+		for _, r := range ranges {
+			if !r.matches(offer) {
+				continue
+			}
+			if r.quality > bestQuality || (r.quality == bestQuality && r.quality > 0 && r.specificity() > bestSpecificity) {
+				best, bestQuality, bestSpecificity = offer, r.quality, r.specificity()
+			}
+			break
+		}
+	}
+
+	return best, best != ""
+}
@@ -0,0 +1,102 @@
+package whiskey
+
+import (
+	"html/template"
+	"net/http"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{MimeTypeJSON, MimeTypeXML, MimeTypeText}
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		wantOk bool
+	}{
+		{name: "Empty Accept picks first offer", accept: "", want: MimeTypeJSON, wantOk: true},
+		{name: "Exact match", accept: "application/xml", want: MimeTypeXML, wantOk: true},
+		{name: "Wildcard picks first offer", accept: "*/*", want: MimeTypeJSON, wantOk: true},
+		{name: "Quality values", accept: "application/json;q=0.5, text/plain;q=0.9", want: MimeTypeText, wantOk: true},
+		{name: "Specific range beats wildcard at equal quality", accept: "*/*, application/xml", want: MimeTypeXML, wantOk: true},
+		{name: "Subtype wildcard", accept: "text/*", want: MimeTypeText, wantOk: true},
+		{name: "Zero quality excludes an offer", accept: "application/json;q=0, */*;q=0.1", want: MimeTypeXML, wantOk: true},
+		{name: "Specific zero quality overrides wildcard", accept: "*/*, application/json;q=0", want: MimeTypeXML, wantOk: true},
+		{name: "Nothing acceptable", accept: "image/png", wantOk: false},
+		{name: "Invalid ranges are ignored", accept: "garbage, text/plain;q=abc, application/xml", want: MimeTypeXML, wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := negotiate(tt.accept, offers)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("Expected (%q, %v) but got (%q, %v)", tt.want, tt.wantOk, got, ok)
+			}
+		})
+	}
+}
+
+func TestContextNegotiate(t *testing.T) {
+	type responseData struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	engine := New()
+	tmpl := template.Must(template.New("user").Parse(`<p>{{.Name}}</p>`))
+	engine.RegisterEncoder(MimeTypeHTML, HtmlTemplateEncoder(tmpl, "user"))
+
+	tests := []struct {
+		name        string
+		accept      string
+		wantType    string
+		wantBody    string
+		wantErrCode int
+	}{
+		{name: "JSON by default", accept: "", wantType: MimeTypeJSON, wantBody: `{"name":"john"}`},
+		{name: "XML", accept: "application/xml", wantType: MimeTypeXML, wantBody: `<responseData><name>john</name></responseData>`},
+		{name: "Plain text", accept: "text/plain", wantType: MimeTypeText, wantBody: `{john}`},
+		{name: "HTML template", accept: "text/html,application/xhtml+xml;q=0.9", wantType: MimeTypeHTML, wantBody: `<p>john</p>`},
+		{name: "Not acceptable", accept: "image/png", wantErrCode: http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := RequestContext{
+				request: HttpRequest{
+					headers: map[string]string{HeaderAccept: tt.accept},
+				},
+				response: &HttpResponse{
+					headers: map[string]string{HeaderVary: "Origin"},
+				},
+				engine: &engine,
+			}
+
+			err := ctx.Negotiate(http.StatusCreated, responseData{Name: "john"})
+			if ctx.response.headers[HeaderVary] != "Origin, Accept" {
+				t.Fatalf("expected Vary header to include Accept, got %q", ctx.response.headers[HeaderVary])
+			}
+
+			if tt.wantErrCode != 0 {
+				httpErr, ok := err.(HttpError)
+				if !ok || httpErr.StatusCode != tt.wantErrCode {
+					t.Fatalf("expected HttpError with status %d, got %v", tt.wantErrCode, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if ctx.response.headers[HeaderContentType] != tt.wantType {
+				t.Fatalf("expected content type %s, got %s", tt.wantType, ctx.response.headers[HeaderContentType])
+			}
+			if string(ctx.response.body) != tt.wantBody {
+				t.Fatalf("expected body %s, got %s", tt.wantBody, ctx.response.body)
+			}
+			if ctx.response.statusCode != http.StatusCreated {
+				t.Fatalf("expected status code %d, got %d", http.StatusCreated, ctx.response.statusCode)
+			}
+		})
+	}
+}
@@ -15,25 +15,53 @@ type RequestParser func(data []string) (HttpRequest, error) // Parse takes a lis
 
 // HTTP_1_1_Parser parses the incoming data according to HTTP 1.1 Specification
 func HTTP_1_1_Parser(requestData string) (HttpRequest, error) {
+	if len(requestData) == 0 {
+		return HttpRequest{}, fmt.Errorf("invalid HTTP request")
+	}
+
+	head, body, _ := strings.Cut(requestData, "\r\n\r\n") // The headers and body are seprated by \r\n\r\n
+	request, err := parseRequestHead(head)
+	if err != nil {
+		return request, err
+	}
+
+	contentLength, ok := request.getHeader(HeaderContentLength)
+	if !ok {
+		if len(body) > 0 {
+			return HttpRequest{}, errors.New("request body not expected since Content-Length is 0")
+		}
+	} else {
+		expectedLength, err := strconv.Atoi(contentLength)
+		if err != nil {
+			return HttpRequest{}, errors.New("invalid Content-Length header value")
+		}
+
+		if expectedLength < len(body) {
+			return HttpRequest{}, errors.New("body length higher than expected")
+		}
+
+		if expectedLength > len(body) {
+			return HttpRequest{}, errors.New("incomplete body")
+		}
+	}
+
+	request.body = []byte(body)
+
+	return request, nil
+}
+
+// parseRequestHead parses the request line and headers of a request. The body is left to the caller
+func parseRequestHead(head string) (HttpRequest, error) {
 	request := HttpRequest{
 		headers:     make(map[string]string),
 		queryParams: make(map[string]string),
+		query:       make(map[string][]string),
 		pathParams:  make(map[string]string),
 	}
 
-	if len(requestData) == 0 {
-		return request, fmt.Errorf("invalid HTTP request")
-	}
-
-	requestParts := strings.SplitN(requestData, "\r\n\r\n", 2) // The headers and body are seprated by \r\n\r\n
-	headerParts := strings.Split(requestParts[0], "\r\n")
+	headerParts := strings.Split(head, "\r\n")
 	protocolLine := headerParts[0]
 
-	var body string
-	if len(requestParts) == 2 {
-		body = requestParts[1]
-	}
-
 	var headers []string
 	if len(headerParts) > 1 {
 		headers = headerParts[1:]
@@ -63,8 +91,8 @@ func HTTP_1_1_Parser(requestData string) (HttpRequest, error) {
 	}
 
 	if len(pathParts) > 1 {
-		queryParamsStr := pathParts[1]
-		request.queryParams = parseQueryParams(queryParamsStr)
+		request.query = parseQuery(pathParts[1])
+		request.queryParams = firstValues(request.query)
 	}
 
 	// We currently only support HTTP/1.1
@@ -84,48 +112,68 @@ func HTTP_1_1_Parser(requestData string) (HttpRequest, error) {
 		request.headers[key] = value
 	}
 
-	contentLength, ok := request.headers[HeaderContentLength]
-	if !ok {
-		if len(body) > 0 {
-			return HttpRequest{}, errors.New("request body not expected since Content-Length is 0")
+	return request, nil
+}
+
+// parseQuery parses a raw query string into every value sent for each key.
+// Keys without a value (`?debug`) map to an empty string, repeated keys (`?tag=a&tag=b`) keep all their values in order,
+// and bracket syntax is normalized so that `ids[]=1` is stored under `ids` and `filter[name]=x` under `filter.name`
+func parseQuery(queryStr string) map[string][]string {
+	query := make(map[string][]string)
+	if queryStr == "" {
+		return query
+	}
+
+	params := strings.SplitSeq(queryStr, "&")
+	for param := range params {
+		if param == "" {
+			continue
 		}
-	} else {
-		expectedLength, err := strconv.Atoi(contentLength)
+
+		rawKey, rawValue, _ := strings.Cut(param, "=")
+		key, err := url.QueryUnescape(rawKey)
 		if err != nil {
-			return HttpRequest{}, errors.New("invalid Content-Length header value")
+			log.Printf("Err parsing query param %v\n", err)
+			// Ignore faulty query params
+			continue
 		}
 
-		if expectedLength < len(body) {
-			return HttpRequest{}, errors.New("body length higher than expected")
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			log.Printf("Err parsing query param %v\n", err)
+			// Ignore faulty query params
+			continue
 		}
 
-		if expectedLength > len(body) {
-			return HttpRequest{}, errors.New("incomplete body")
+		key = normalizeQueryKey(key)
+		if key == "" {
+			continue
 		}
-	}
 
-	request.body = []byte(body)
+		query[key] = append(query[key], value)
+	}
 
-	return request, nil
+	return query
 }
 
-func parseQueryParams(queryParamsStr string) map[string]string {
-	queryParams := make(map[string]string)
-	if queryParamsStr == "" {
-		return queryParams
+// normalizeQueryKey rewrites bracket syntax into dotted keys. `ids[]` becomes `ids` and `filter[name][first]` becomes `filter.name.first`
+func normalizeQueryKey(key string) string {
+	key = strings.TrimSuffix(key, "[]")
+	if !strings.Contains(key, "[") {
+		return key
 	}
-	params := strings.SplitSeq(queryParamsStr, "&")
-	for param := range params {
-		paramParts := strings.Split(param, "=")
-		value, err := url.QueryUnescape(paramParts[1])
-		if err != nil {
-			log.Printf("Err parsing query param %v\n", err)
-			// Ignore faulty query params
-			continue
-		}
 
-		queryParams[paramParts[0]] = value
-	}
+	key = strings.ReplaceAll(key, "[]", "")
+	key = strings.ReplaceAll(key, "]", "")
+	return strings.ReplaceAll(key, "[", ".")
+}
 
-	return queryParams
+func firstValues(values map[string][]string) map[string]string {
+	m := make(map[string]string, len(values))
+	for key, vals := range values {
+		if len(vals) > 0 {
+			m[key] = vals[0]
+		}
+	}
+	return m
 }
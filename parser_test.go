@@ -2,6 +2,7 @@ package whiskey
 
 import (
 	"net/http"
+	"reflect"
 	"testing"
 )
 
@@ -40,6 +41,9 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 				queryParams: map[string]string{
 					"query1": "query2",
 				},
+				query: map[string][]string{
+					"query1": {"query2"},
+				},
 				headers: map[string]string{
 					"Host": "localhost",
 				},
@@ -59,6 +63,10 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 					"id":     "123",
 					"action": "update",
 				},
+				query: map[string][]string{
+					"id":     {"123"},
+					"action": {"update"},
+				},
 				headers: map[string]string{
 					"Host":          "api.example.com",
 					"Content-Type":  "application/json",
@@ -171,6 +179,12 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 					"limit": "10",
 					"sort":  "desc",
 				},
+				query: map[string][]string{
+					"q":     {"test"},
+					"page":  {"1"},
+					"limit": {"10"},
+					"sort":  {"desc"},
+				},
 				headers: map[string]string{
 					"Host": "search.example.com",
 				},
@@ -188,6 +202,10 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 					"category": "books",
 					"price":    "",
 				},
+				query: map[string][]string{
+					"category": {"books"},
+					"price":    {""},
+				},
 				headers: map[string]string{
 					"Host": "store.example.com",
 				},
@@ -205,6 +223,10 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 					"q":   "hello world",
 					"tag": "example:test",
 				},
+				query: map[string][]string{
+					"q":   {"hello world"},
+					"tag": {"example:test"},
+				},
 				headers: map[string]string{
 					"Host": "example.com",
 				},
@@ -421,6 +443,10 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 					"q":       "test",
 					"invalid": "",
 				},
+				query: map[string][]string{
+					"q":       {"test"},
+					"invalid": {""},
+				},
 				headers: map[string]string{
 					"Host": "example.com",
 				},
@@ -482,6 +508,21 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Edge case - Lowercase Content-Length with body",
+			requestData: "POST /u/5 HTTP/1.1\r\n" +
+				"content-length: 2\r\n\r\n{}",
+			want: HttpRequest{
+				path:        "/u/5",
+				method:      http.MethodPost,
+				body:        []byte("{}"),
+				queryParams: map[string]string{},
+				headers: map[string]string{
+					"content-length": "2",
+				},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -504,3 +545,69 @@ func TestHTTP_1_1_Parser(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  map[string][]string
+	}{
+		{
+			name:  "Empty query",
+			query: "",
+			want:  map[string][]string{},
+		},
+		{
+			name:  "Valueless key",
+			query: "debug&verbose=",
+			want:  map[string][]string{"debug": {""}, "verbose": {""}},
+		},
+		{
+			name:  "Repeated keys keep order",
+			query: "tag=a&tag=b&tag=c",
+			want:  map[string][]string{"tag": {"a", "b", "c"}},
+		},
+		{
+			name:  "Bracket array syntax",
+			query: "ids[]=1&ids[]=2&ids%5B%5D=3",
+			want:  map[string][]string{"ids": {"1", "2", "3"}},
+		},
+		{
+			name:  "Bracket nested syntax",
+			query: "filter[name]=john&filter[address][city]=paris",
+			want:  map[string][]string{"filter.name": {"john"}, "filter.address.city": {"paris"}},
+		},
+		{
+			name:  "Plus as space",
+			query: "q=hello+world&name=a%2Bb",
+			want:  map[string][]string{"q": {"hello world"}, "name": {"a+b"}},
+		},
+		{
+			name:  "Value containing equals sign",
+			query: "expr=a=b",
+			want:  map[string][]string{"expr": {"a=b"}},
+		},
+		{
+			name:  "Empty segments and invalid escapes are skipped",
+			query: "a=1&&b=%zz&=orphan",
+			want:  map[string][]string{"a": {"1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseQuery(tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFirstQueryValues(t *testing.T) {
+	got := firstValues(parseQuery("tag=a&tag=b&debug"))
+	want := map[string]string{"tag": "a", "debug": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+}
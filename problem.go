@@ -0,0 +1,102 @@
+package whiskey
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+)
+
+// Problem is an RFC 9457 problem details object. Returned from a handler, it is sent as application/problem+json
+type Problem struct {
+	Type       string         `json:"type,omitempty"`     // URI identifying the problem type. Defaults to about:blank
+	Title      string         `json:"title,omitempty"`    // Short summary of the problem type
+	Status     int            `json:"status,omitempty"`   // HTTP status code
+	Detail     string         `json:"detail,omitempty"`   // Explanation specific to this occurrence of the problem
+	Instance   string         `json:"instance,omitempty"` // URI identifying this occurrence of the problem
+	Extensions map[string]any `json:"-"`                  // Additional members, sent alongside the standard ones
+	Err        error          `json:"-"`                  // The underlying error, if any. It's never sent to the client
+}
+
+// NewProblem returns a problem of type about:blank whose title is the status text of statusCode
+func NewProblem(statusCode int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+	}
+}
+
+func (p Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// Unwrap returns the underlying error so errors.Is and errors.As can look through a Problem
+func (p Problem) Unwrap() error {
+	return p.Err
+}
+
+// WithExtension returns a copy of the problem with an additional member
+func (p Problem) WithExtension(key string, value any) Problem {
+	extensions := maps.Clone(p.Extensions)
+	if extensions == nil {
+		extensions = make(map[string]any)
+	}
+	extensions[key] = value
+	p.Extensions = extensions
+	return p
+}
+
+// MarshalJSON writes the extensions as top level members. They can't override the standard members
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	maps.Copy(members, p.Extensions)
+
+	// The standard members are marshalled through an alias so they keep their omitempty behaviour
+	type problem Problem
+	standard, err := json.Marshal(problem(p))
+	if err != nil {
+		return nil, err
+	}
+
+	var standardMembers map[string]any
+	if err := json.Unmarshal(standard, &standardMembers); err != nil {
+		return nil, err
+	}
+	maps.Copy(members, standardMembers)
+
+	return json.Marshal(members)
+}
+
+// Problem converts the HttpError into problem details. The detail is the message of the error and any extra fields of its body become extensions
+func (h HttpError) Problem() Problem {
+	problem := NewProblem(h.StatusCode, h.Message)
+	if problem.Detail == problem.Title {
+		problem.Detail = ""
+	}
+	problem.Err = h.Err
+	for key, value := range h.fields {
+		problem = problem.WithExtension(key, value)
+	}
+	return problem
+}
+
+func writeProblem(ctx Context, problem Problem) error {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if requestID, ok := ctx.GetString(RequestIDKey); ok && requestID != "" {
+		if _, exists := problem.Extensions["request_id"]; !exists {
+			problem = problem.WithExtension("request_id", requestID)
+		}
+	}
+
+	b, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	return ctx.Bytes(problem.Status, MimeTypeProblemJSON, b)
+}
@@ -0,0 +1,310 @@
+package whiskey
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitState is what limiters keep per key in a RateLimitStore. Each limiter only uses some of the fields
+type RateLimitState struct {
+	Tokens    float64   // Tokens left in the bucket of a token bucket limiter
+	Last      time.Time // When the bucket was last refilled, or when the current window of a sliding window limiter started
+	Count     int       // Requests in the current window of a sliding window limiter
+	PrevCount int       // Requests in the previous window of a sliding window limiter
+}
+
+// RateLimitStore keeps the state of rate limiters. Implement it to share limits between servers, e.g. backed by Redis
+type RateLimitStore interface {
+	// Update calls update with the state of key, the zero state if there's none, and stores the state it leaves behind until ttl passes.
+	// Updates of the same key must not run concurrently, otherwise requests may slip through the limit
+	Update(ctx context.Context, key string, ttl time.Duration, update func(state *RateLimitState)) error
+}
+
+// RateLimitResult is the decision of a RateLimiter for a single request
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // Requests allowed per window, or the size of the bucket
+	Remaining  int           // Requests left before being limited
+	Reset      time.Duration // Time until the full quota is available again
+	RetryAfter time.Duration // Time until the next request is allowed. It's only set when the request isn't allowed
+	Policy     string        // The quota policy, sent in the RateLimit-Policy header, e.g. "100;w=60"
+}
+
+// RateLimiter decides whether a request identified by key is allowed
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+type tokenBucket struct {
+	store  RateLimitStore
+	rate   float64 // Tokens added per second
+	burst  int
+	period time.Duration
+	now    func() time.Time
+}
+
+// NewTokenBucket returns a limiter allowing limit requests per period, refilled continuously, with bursts of up to burst requests
+func NewTokenBucket(store RateLimitStore, limit int, period time.Duration, burst int) RateLimiter {
+	if limit <= 0 || period <= 0 || burst <= 0 {
+		panic("whiskey: token bucket limit, period and burst must be positive")
+	}
+	return &tokenBucket{
+		store:  store,
+		rate:   float64(limit) / period.Seconds(),
+		burst:  burst,
+		period: period,
+		now:    time.Now,
+	}
+}
+
+func (b *tokenBucket) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	result := RateLimitResult{
+		Limit:  b.burst,
+		Policy: fmt.Sprintf("%d;w=%d", int(math.Round(b.rate*b.period.Seconds())), ceilSeconds(b.period)),
+	}
+	fillTime := time.Duration(float64(b.burst) / b.rate * float64(time.Second))
+
+	err := b.store.Update(ctx, key, fillTime, func(state *RateLimitState) {
+		now := b.now()
+		if state.Last.IsZero() {
+			state.Tokens = float64(b.burst)
+		} else {
+			elapsed := now.Sub(state.Last).Seconds()
+			state.Tokens = min(float64(b.burst), state.Tokens+elapsed*b.rate)
+		}
+		state.Last = now
+
+		if state.Tokens >= 1 {
+			state.Tokens--
+			result.Allowed = true
+		} else {
+			result.RetryAfter = secondsToDuration((1 - state.Tokens) / b.rate)
+		}
+
+		result.Remaining = int(state.Tokens)
+		result.Reset = secondsToDuration((float64(b.burst) - state.Tokens) / b.rate)
+	})
+
+	return result, err
+}
+
+type slidingWindow struct {
+	store  RateLimitStore
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+// NewSlidingWindow returns a limiter allowing limit requests in any window of the given length.
+// Like most sliding window limiters it weighs the count of the previous window by how much of it still overlaps, instead of keeping every timestamp
+func NewSlidingWindow(store RateLimitStore, limit int, window time.Duration) RateLimiter {
+	if limit <= 0 || window <= 0 {
+		panic("whiskey: sliding window limit and window must be positive")
+	}
+	return &slidingWindow{
+		store:  store,
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+func (s *slidingWindow) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	result := RateLimitResult{
+		Limit:  s.limit,
+		Policy: fmt.Sprintf("%d;w=%d", s.limit, ceilSeconds(s.window)),
+	}
+
+	err := s.store.Update(ctx, key, 2*s.window, func(state *RateLimitState) {
+		now := s.now()
+		windowStart := now.Truncate(s.window)
+
+		switch {
+		case state.Last.Equal(windowStart):
+		case state.Last.Add(s.window).Equal(windowStart):
+			state.PrevCount, state.Count = state.Count, 0
+		default:
+			state.PrevCount, state.Count = 0, 0
+		}
+		state.Last = windowStart
+
+		elapsed := now.Sub(windowStart)
+		overlap := 1 - elapsed.Seconds()/s.window.Seconds()
+		weighted := float64(state.PrevCount)*overlap + float64(state.Count)
+
+		if weighted+1 <= float64(s.limit) {
+			state.Count++
+			weighted++
+			result.Allowed = true
+		} else {
+			result.RetryAfter = s.retryAfter(state, elapsed)
+		}
+
+		result.Remaining = max(0, s.limit-int(math.Ceil(weighted)))
+		result.Reset = s.window - elapsed
+	})
+
+	return result, err
+}
+
+// retryAfter returns how long it takes until the weighted count of a limited key leaves room for another request
+func (s *slidingWindow) retryAfter(state *RateLimitState, elapsed time.Duration) time.Duration {
+	untilNextWindow := s.window - elapsed
+	room := float64(s.limit - 1 - state.Count)
+	if room < 0 || state.PrevCount == 0 {
+		// The current window alone is full, so the next request must wait for it to become the previous one
+		return untilNextWindow
+	}
+
+	// Solve PrevCount * (1 - t/window) + Count + 1 <= limit for the elapsed time t
+	needed := time.Duration((1 - room/float64(state.PrevCount)) * float64(s.window))
+	return min(max(needed-elapsed, 0), untilNextWindow)
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+const memoryStoreShards = 32
+
+// MemoryRateLimitStore keeps rate limit state in memory, split into shards so requests for different keys rarely wait on each other
+type MemoryRateLimitStore struct {
+	seed   maphash.Seed
+	shards [memoryStoreShards]memoryStoreShard
+	now    func() time.Time
+}
+
+type memoryStoreShard struct {
+	mu      sync.Mutex
+	entries map[string]*memoryStoreEntry
+	updates int // Updates since expired entries were last removed
+}
+
+type memoryStoreEntry struct {
+	state   RateLimitState
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store. Expired keys are removed as the store is used
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	store := &MemoryRateLimitStore{seed: maphash.MakeSeed(), now: time.Now}
+	for idx := range store.shards {
+		store.shards[idx].entries = make(map[string]*memoryStoreEntry)
+	}
+	return store
+}
+
+func (m *MemoryRateLimitStore) Update(ctx context.Context, key string, ttl time.Duration, update func(state *RateLimitState)) error {
+	shard := &m.shards[maphash.String(m.seed, key)%memoryStoreShards]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := m.now()
+	shard.updates++
+	if shard.updates >= 1000 {
+		shard.removeExpired(now)
+	}
+
+	entry, ok := shard.entries[key]
+	if !ok || now.After(entry.expires) {
+		entry = &memoryStoreEntry{}
+		shard.entries[key] = entry
+	}
+
+	update(&entry.state)
+	entry.expires = now.Add(ttl)
+	return nil
+}
+
+func (s *memoryStoreShard) removeExpired(now time.Time) {
+	s.updates = 0
+	for key, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// RateLimitConfig configures the RateLimit middleware
+type RateLimitConfig struct {
+	Limiter RateLimiter
+	// KeyFunc returns the key requests are counted by. Requests for which it returns an empty key aren't limited.
+	// Defaults to RateLimitByIP. It runs after the middlewares before it, so it can use Context data set by them, e.g. an authenticated user
+	KeyFunc func(ctx Context) string
+	Error   error // Returned to the global error handler when a request is limited. Defaults to a 429 Too Many Requests HttpError
+}
+
+// RateLimit returns a middleware limiting every client IP with limiter. Add it with Use to limit all routes, or to the handlers of a route to limit it on its own
+func RateLimit(limiter RateLimiter) HttpHandler {
+	return RateLimitWithConfig(RateLimitConfig{Limiter: limiter})
+}
+
+// RateLimitWithConfig returns a RateLimit middleware with custom configuration.
+// Every response gets RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, and limited ones a Retry-After header
+func RateLimitWithConfig(config RateLimitConfig) HttpHandler {
+	if config.Limiter == nil {
+		panic("whiskey: rate limit middleware requires a limiter")
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = RateLimitByIP
+	}
+	if config.Error == nil {
+		config.Error = NewHTTPErrorWithMessage(http.StatusTooManyRequests, "too many requests", BodyTypeJSON)
+	}
+
+	return func(ctx Context) error {
+		key := config.KeyFunc(ctx)
+		if key == "" {
+			return nil
+		}
+
+		result, err := config.Limiter.Allow(ctx.Context(), key)
+		if err != nil {
+			return fmt.Errorf("rate limiting request: %w", err)
+		}
+
+		ctx.SetHeader(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+		ctx.SetHeader(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+		ctx.SetHeader(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+		if result.Policy != "" {
+			ctx.SetHeader(HeaderRateLimitPolicy, result.Policy)
+		}
+
+		if !result.Allowed {
+			ctx.SetHeader(HeaderRetryAfter, strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			return config.Error
+		}
+		return nil
+	}
+}
+
+// RateLimitByIP counts requests by the IP address of the client
+func RateLimitByIP(ctx Context) string {
+	addr := ctx.RemoteAddr()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// RateLimitByRoute wraps keyFunc so every route pattern is limited separately, e.g. RateLimitByRoute(RateLimitByIP) limits every IP per route
+func RateLimitByRoute(keyFunc func(ctx Context) string) func(ctx Context) string {
+	return func(ctx Context) string {
+		key := keyFunc(ctx)
+		if key == "" {
+			return ""
+		}
+		return ctx.Method() + " " + ctx.Route() + "|" + key
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,192 @@
+package whiskey
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewTokenBucket(NewMemoryRateLimitStore(), 1, time.Second, 3).(*tokenBucket)
+	limiter.now = clock.Now
+
+	for idx := range 3 {
+		result, _ := limiter.Allow(context.Background(), "client")
+		if !result.Allowed || result.Remaining != 2-idx {
+			t.Fatalf("expected burst request %d to be allowed, got %+v", idx, result)
+		}
+	}
+
+	result, _ := limiter.Allow(context.Background(), "client")
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Fatalf("expected the empty bucket to limit, got %+v", result)
+	}
+
+	result, _ = limiter.Allow(context.Background(), "other")
+	if !result.Allowed {
+		t.Fatal("expected keys to be limited separately")
+	}
+
+	clock.Advance(time.Second)
+	result, _ = limiter.Allow(context.Background(), "client")
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected a refilled token to be allowed, got %+v", result)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewSlidingWindow(NewMemoryRateLimitStore(), 4, time.Minute).(*slidingWindow)
+	limiter.now = clock.Now
+
+	for range 4 {
+		if result, _ := limiter.Allow(context.Background(), "client"); !result.Allowed {
+			t.Fatalf("expected requests within the limit to be allowed, got %+v", result)
+		}
+	}
+
+	result, _ := limiter.Allow(context.Background(), "client")
+	if result.Allowed || result.RetryAfter != time.Minute || result.Remaining != 0 {
+		t.Fatalf("expected the full window to limit, got %+v", result)
+	}
+
+	// Halfway through the next window, half of the previous window's 4 requests still count
+	clock.Advance(90 * time.Second)
+	for range 2 {
+		if result, _ := limiter.Allow(context.Background(), "client"); !result.Allowed {
+			t.Fatalf("expected requests within the weighted limit to be allowed, got %+v", result)
+		}
+	}
+
+	result, _ = limiter.Allow(context.Background(), "client")
+	if result.Allowed || result.RetryAfter != 15*time.Second {
+		t.Fatalf("expected the weighted count to limit until a previous request slides out, got %+v", result)
+	}
+
+	clock.Advance(15 * time.Second)
+	if result, _ := limiter.Allow(context.Background(), "client"); !result.Allowed {
+		t.Fatalf("expected a request to be allowed once the window slid, got %+v", result)
+	}
+}
+
+func TestMemoryRateLimitStoreConcurrentUpdates(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				store.Update(context.Background(), "key", time.Minute, func(state *RateLimitState) {
+					state.Count++
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	var count int
+	store.Update(context.Background(), "key", time.Minute, func(state *RateLimitState) {
+		count = state.Count
+	})
+	if count != 5000 {
+		t.Errorf("expected 5000 updates, got %d", count)
+	}
+}
+
+func TestMemoryRateLimitStoreExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.Now
+
+	store.Update(context.Background(), "key", time.Second, func(state *RateLimitState) {
+		state.Count = 5
+	})
+	clock.Advance(2 * time.Second)
+	store.Update(context.Background(), "key", time.Second, func(state *RateLimitState) {
+		if state.Count != 0 {
+			t.Errorf("expected expired state to be reset, got %+v", state)
+		}
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	w := newTestServer()
+	w.Use(func(ctx Context) error {
+		if apiKey, ok := ctx.GetHeader("X-Api-Key"); ok {
+			ctx.Set("apiKey", apiKey)
+		}
+		return nil
+	})
+	w.Use(RateLimitWithConfig(RateLimitConfig{
+		Limiter: NewSlidingWindow(NewMemoryRateLimitStore(), 1, time.Hour),
+		KeyFunc: func(ctx Context) string {
+			apiKey, _ := ctx.GetString("apiKey")
+			return apiKey
+		},
+	}))
+	w.GET("/hello", func(ctx Context) error {
+		return ctx.String(200, "hello")
+	})
+
+	response := serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\nX-Api-Key: team-a\r\n\r\n")
+	expected := []string{"HTTP/1.1 200 OK", "RateLimit-Limit: 1\r\n", "RateLimit-Remaining: 0\r\n", "RateLimit-Policy: 1;w=3600\r\n", "RateLimit-Reset: "}
+	for _, header := range expected {
+		if !strings.Contains(response, header) {
+			t.Errorf("expected %q in %q", header, response)
+		}
+	}
+
+	response = serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\nX-Api-Key: team-a\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 429 Too Many Requests") || !strings.Contains(response, "Retry-After: ") || !strings.HasSuffix(response, `{"error":"too many requests"}`) {
+		t.Errorf("expected the second request to be limited, got %q", response)
+	}
+
+	response = serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\nX-Api-Key: team-b\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 200 OK") {
+		t.Errorf("expected other keys to have their own limit, got %q", response)
+	}
+
+	response = serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 200 OK") || strings.Contains(response, "RateLimit-Limit") {
+		t.Errorf("expected requests without a key not to be limited, got %q", response)
+	}
+}
+
+func TestRateLimitByRoute(t *testing.T) {
+	w := newTestServer()
+	w.Use(RateLimitWithConfig(RateLimitConfig{
+		Limiter: NewTokenBucket(NewMemoryRateLimitStore(), 1, time.Hour, 1),
+		KeyFunc: RateLimitByRoute(RateLimitByIP),
+	}))
+	w.GET("/a", func(ctx Context) error { return ctx.String(200, "a") })
+	w.GET("/b", func(ctx Context) error { return ctx.String(200, "b") })
+
+	for _, path := range []string{"/a", "/b"} {
+		response := serve(t, w, "GET "+path+" HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		if !strings.HasPrefix(response, "HTTP/1.1 200 OK") {
+			t.Errorf("expected the first request to %s to be allowed, got %q", path, response)
+		}
+	}
+
+	response := serve(t, w, "GET /a HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 429") || !strings.Contains(response, "Retry-After: 3600\r\n") {
+		t.Errorf("expected the second request to /a to be limited, got %q", response)
+	}
+}
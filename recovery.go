@@ -0,0 +1,58 @@
+package whiskey
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is passed to the global error handler when a handler panics
+type PanicError struct {
+	Value any    // The value the handler panicked with
+	Stack []byte // Stack trace of the goroutine that panicked
+}
+
+func newPanicError(value any) *PanicError {
+	// A panic that was already converted, e.g. by the Timeout middleware on another goroutine, keeps its original stack
+	if panicErr, ok := value.(*PanicError); ok {
+		return panicErr
+	}
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap returns the value the handler panicked with if it is an error
+func (p *PanicError) Unwrap() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// runHandlers runs the handler chain and converts a panic in any handler into a *PanicError, so it reaches the global error handler instead of killing the connection
+func (w *Whiskey) runHandlers(ctx *RequestContext) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			panicErr := newPanicError(p)
+			w.errorLogger.Printf("Recovered from panic: %v\n%s", panicErr.Value, panicErr.Stack)
+			err = panicErr
+		}
+	}()
+
+	return ctx.Next()
+}
+
+// handleError maps err with the registered error mappings and passes it to the global error handler. A panic in the error handler itself is reported as an error
+func (w *Whiskey) handleError(err error, ctx *RequestContext) (handlerErr error) {
+	defer func() {
+		if p := recover(); p != nil {
+			panicErr := newPanicError(p)
+			w.errorLogger.Printf("Recovered from panic in error handler: %v\n%s", panicErr.Value, panicErr.Stack)
+			handlerErr = panicErr
+		}
+	}()
+
+	return w.router.errorHandler(w.mapError(err), ctx)
+}
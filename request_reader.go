@@ -1,19 +1,49 @@
 package whiskey
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"log"
+	"mime"
+	"strconv"
+	"strings"
 )
 
-func readRequest(reader io.Reader) (HttpRequest, error) {
+// ErrRequestBodyTooLarge is returned when the Content-Length of a request is higher than ServerConfig.MaxRequestBodySize
+var ErrRequestBodyTooLarge = errors.New("request body too large")
+
+// readRequest reads the request head and then exactly as many body bytes as the Content-Length header announces.
+// multipart/form-data bodies are not read, the request gets a reader over the rest of the body instead so uploads are streamed from the connection.
+// A maxBodySize of 0 or less disables the body size check
+func readRequest(reader io.Reader, maxBodySize int64) (HttpRequest, error) {
 	tmp := make([]byte, 1024)
 
 	// Size is 0 since we don't know how much total data we will read
 	data := make([]byte, 0)
-	length := 0
+	headerEnd := -1
+	var expectedLength int64
+	streamBody := false
 
 	for {
 		n, err := reader.Read(tmp)
+		data = append(data, tmp[:n]...)
+
+		if headerEnd < 0 {
+			if idx := bytes.Index(data, []byte("\r\n\r\n")); idx >= 0 {
+				headerEnd = idx + 4
+				expectedLength = headContentLength(data[:idx])
+				if maxBodySize > 0 && expectedLength > maxBodySize {
+					return HttpRequest{}, ErrRequestBodyTooLarge
+				}
+				streamBody = expectedLength > 0 && headMediaType(data[:idx]) == MimeTypeMultipartForm
+			}
+		}
+
+		if headerEnd >= 0 && (streamBody || int64(len(data)-headerEnd) >= expectedLength) {
+			break
+		}
+
 		if err != nil {
 			if err == io.EOF {
 				log.Println("Connection Closed...")
@@ -21,19 +51,60 @@ func readRequest(reader io.Reader) (HttpRequest, error) {
 			log.Printf("Error reading from connection, err: %+v", err)
 			break
 		}
+	}
 
-		data = append(data, tmp[:n]...)
-		length += n
-
-		if n < 1024 {
-			break
+	if streamBody {
+		request, err := parseRequestHead(string(data[:headerEnd-4]))
+		if err != nil {
+			return HttpRequest{}, err
 		}
+		// Part of the body may have arrived with the head, the rest is still on the connection
+		request.bodyReader = io.LimitReader(io.MultiReader(bytes.NewReader(data[headerEnd:]), reader), expectedLength)
+		return request, nil
 	}
 
 	// Parse the request line
 	return parseRequest(string(data))
 }
 
+// headContentLength looks up the Content-Length header in the raw request head. Missing or invalid values are treated as 0 and left for the parser to reject
+func headContentLength(head []byte) int64 {
+	value, ok := headHeader(head, HeaderContentLength)
+	if !ok {
+		return 0
+	}
+
+	length, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || length < 0 {
+		return 0
+	}
+	return length
+}
+
+// headMediaType returns the media type of the Content-Type header in the raw request head, empty if it is missing or invalid
+func headMediaType(head []byte) string {
+	value, ok := headHeader(head, HeaderContentType)
+	if !ok {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// headHeader looks up a header in the raw request head ignoring the case of its name
+func headHeader(head []byte, key string) (string, bool) {
+	for line := range strings.SplitSeq(string(head), "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), key) {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
 func parseRequest(requestData string) (HttpRequest, error) {
 	return HTTP_1_1_Parser(requestData)
 }
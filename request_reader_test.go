@@ -0,0 +1,81 @@
+package whiskey
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadRequest(t *testing.T) {
+	body := strings.Repeat("a", 3000)
+	tests := []struct {
+		name        string
+		requestData string
+		maxBodySize int64
+		wantBody    string
+		wantErr     error
+	}{
+		{
+			name:        "Request without body",
+			requestData: "GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			wantBody:    "",
+		},
+		{
+			name:        "Body larger than a single read",
+			requestData: "POST /hello HTTP/1.1\r\nContent-Length: 3000\r\n\r\n" + body,
+			maxBodySize: 4096,
+			wantBody:    body,
+		},
+		{
+			name:        "Body over the configured limit",
+			requestData: "POST /hello HTTP/1.1\r\ncontent-length: 3000\r\n\r\n" + body,
+			maxBodySize: 1024,
+			wantErr:     ErrRequestBodyTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// OneByteReader makes sure the body is assembled across many reads
+			got, err := readRequest(iotest.OneByteReader(strings.NewReader(tt.requestData)), tt.maxBodySize)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected error %v but got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected to not error but got error %+v", err)
+			}
+			if string(got.body) != tt.wantBody {
+				t.Errorf("Expected body of length %d but got %d", len(tt.wantBody), len(got.body))
+			}
+		})
+	}
+}
+
+func TestReadRequestStreamsMultipartBody(t *testing.T) {
+	body := "--x\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n" + strings.Repeat("a", 3000) + "\r\n--x--\r\n"
+	conn := strings.NewReader("POST /upload HTTP/1.1\r\nContent-Type: multipart/form-data; boundary=x\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body + "trailing bytes")
+
+	got, err := readRequest(conn, 4096)
+	if err != nil {
+		t.Fatalf("Expected to not error but got error %+v", err)
+	}
+	if len(got.body) != 0 || got.bodyReader == nil {
+		t.Fatalf("Expected the body to be left on the connection, got %d buffered bytes", len(got.body))
+	}
+	if conn.Len() < len(body)-1024 {
+		t.Errorf("Expected only the head to be read, %d bytes are left", conn.Len())
+	}
+
+	streamed, err := io.ReadAll(got.bodyReader)
+	if err != nil || string(streamed) != body {
+		t.Errorf("Expected the body reader to return exactly the body, got %d bytes and %v", len(streamed), err)
+	}
+}
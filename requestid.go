@@ -0,0 +1,87 @@
+package whiskey
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// RequestIDConfig configures the RequestID middleware
+type RequestIDConfig struct {
+	Header    string               // Header the ID is read from and echoed in. Defaults to X-Request-ID
+	Generator func() string        // Generates IDs for requests without a valid one. Defaults to UUIDv7, so IDs sort by time
+	Validator func(id string) bool // Decides whether an incoming ID is kept. Defaults to at most 128 letters, digits, '-', '_', '.' or ':'
+}
+
+// RequestID returns a middleware that assigns every request an ID. A valid ID sent by the client in X-Request-ID is kept, otherwise a UUIDv7 is generated.
+// The ID is stored in the DataStore under RequestIDKey, echoed in the X-Request-ID response header and included in the access log and the default error handler's JSON responses
+func RequestID() HttpHandler {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+// RequestIDWithConfig returns a RequestID middleware with custom configuration
+func RequestIDWithConfig(config RequestIDConfig) HttpHandler {
+	if config.Header == "" {
+		config.Header = HeaderRequestID
+	}
+	if config.Generator == nil {
+		config.Generator = newUUIDv7
+	}
+	if config.Validator == nil {
+		config.Validator = validRequestID
+	}
+
+	return func(ctx Context) error {
+		id, ok := ctx.GetHeader(config.Header)
+		if !ok || !config.Validator(id) {
+			id = config.Generator()
+		}
+
+		ctx.Set(RequestIDKey, id)
+		ctx.SetHeader(config.Header, id)
+		return nil
+	}
+}
+
+// validRequestID accepts IDs that are safe to echo in headers and write to logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		isAlphaNumeric := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlphaNumeric && c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// newUUIDv7 returns a random RFC 9562 version 7 UUID, which starts with the current Unix time in milliseconds
+func newUUIDv7() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[6:]); err != nil {
+		// crypto/rand doesn't fail on supported platforms
+		panic(err)
+	}
+
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(time.Now().UnixMilli()))
+	copy(uuid[:6], timestamp[2:])
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x70 // Version 7
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf[:])
+}
@@ -0,0 +1,97 @@
+package whiskey
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidV7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUIDv7(t *testing.T) {
+	first := newUUIDv7()
+	second := newUUIDv7()
+
+	if !uuidV7Pattern.MatchString(first) {
+		t.Errorf("expected a version 7 UUID, got %s", first)
+	}
+	if first == second {
+		t.Error("expected unique IDs")
+	}
+	// The first 48 bits are the timestamp, so IDs generated later never sort before earlier ones
+	if second[:13] < first[:13] {
+		t.Errorf("expected %s to sort after %s", second, first)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		incoming   string
+		expectKept bool
+	}{
+		{name: "no incoming id"},
+		{name: "valid incoming id", incoming: "abc-123_x.y:z", expectKept: true},
+		{name: "invalid incoming id", incoming: "abc 123<script>"},
+		{name: "too long incoming id", incoming: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored string
+
+			w := newTestServer()
+			w.Use(RequestID())
+			w.GET("/hello", func(ctx Context) error {
+				stored, _ = ctx.GetString(RequestIDKey)
+				return ctx.String(200, "hello")
+			})
+
+			request := "GET /hello HTTP/1.1\r\nHost: localhost\r\n"
+			if tt.incoming != "" {
+				request += "X-Request-Id: " + tt.incoming + "\r\n"
+			}
+			response := serve(t, w, request+"\r\n")
+
+			if tt.expectKept && stored != tt.incoming {
+				t.Errorf("expected the incoming id %q to be kept, got %q", tt.incoming, stored)
+			}
+			if !tt.expectKept && !uuidV7Pattern.MatchString(stored) {
+				t.Errorf("expected a generated id, got %q", stored)
+			}
+			if !strings.Contains(response, "X-Request-ID: "+stored+"\r\n") {
+				t.Errorf("expected the id to be echoed in %q", response)
+			}
+		})
+	}
+}
+
+func TestRequestIDInErrorResponsesAndAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+
+	w := newTestServer()
+	w.AccessLog(AccessLogConfig{Handler: slog.NewJSONHandler(&logs, nil)})
+	w.Use(RequestID())
+	w.GET("/json", func(ctx Context) error {
+		return NewHTTPErrorWithMessage(400, "invalid name", BodyTypeJSON)
+	})
+	w.GET("/problem", func(ctx Context) error {
+		return NewProblem(409, "already exists")
+	})
+
+	response := serve(t, w, "GET /json HTTP/1.1\r\nHost: localhost\r\nX-Request-ID: req-1\r\n\r\n")
+	if !strings.HasSuffix(response, `{"error":"invalid name","request_id":"req-1"}`) {
+		t.Errorf("expected the request id in the error body, got %q", response)
+	}
+
+	response = serve(t, w, "GET /problem HTTP/1.1\r\nHost: localhost\r\nX-Request-ID: req-2\r\n\r\n")
+	if !strings.Contains(response, `"request_id":"req-2"`) {
+		t.Errorf("expected the request id in the problem, got %q", response)
+	}
+
+	if !strings.Contains(logs.String(), `"request_id":"req-1"`) || !strings.Contains(logs.String(), `"request_id":"req-2"`) {
+		t.Errorf("expected the request ids in the access log, got %q", logs.String())
+	}
+}
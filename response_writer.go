@@ -8,20 +8,25 @@ import (
 	"time"
 )
 
-func (w *Whiskey) writeResponse(resp *HttpResponse, writer io.Writer) {
+// writeResponse writes resp to the connection and returns the number of bytes written
+func (w *Whiskey) writeResponse(resp *HttpResponse, writer io.Writer) (int, error) {
 	if err := writer.(net.Conn).SetWriteDeadline(time.Now().Add(w.config.WriteTimeout)); err != nil {
 		w.errorLogger.Println("Unable to set write deadline", err)
-		return
+		return 0, err
 	}
 
 	if resp.statusCode == 0 {
 		resp.statusCode = http.StatusOK
 	}
 
+	written := 0
+
 	// We only support HTTP/1.1
-	if _, err := fmt.Fprintf(writer, "HTTP/1.1 %d %s\r\n", resp.statusCode, http.StatusText(resp.statusCode)); err != nil {
+	n, err := fmt.Fprintf(writer, "HTTP/1.1 %d %s\r\n", resp.statusCode, http.StatusText(resp.statusCode))
+	written += n
+	if err != nil {
 		w.errorLogger.Printf("Unable to write response.. %+v", err)
-		return
+		return written, err
 	}
 
 	contentType, ok := resp.headers[HeaderContentType]
@@ -29,21 +34,26 @@ func (w *Whiskey) writeResponse(resp *HttpResponse, writer io.Writer) {
 		contentType = "text/plain; charset=utf-8"
 	}
 	contentLength := len(resp.body)
-	resp.headers["Content-Length"] = fmt.Sprintf("%d", contentLength)
-	resp.headers["Date"] = time.Now().Format("Mon, 02 January 2006 15:04:05 GMT")
-	resp.headers["Content-Type"] = contentType
+	resp.SetHeader("Content-Length", fmt.Sprintf("%d", contentLength))
+	resp.SetHeader("Date", time.Now().Format("Mon, 02 January 2006 15:04:05 GMT"))
+	resp.SetHeader("Content-Type", contentType)
 
 	// Write the headers to the response stream
 	for key, value := range resp.headers {
-		if _, err := fmt.Fprintf(writer, "%s: %s\r\n", key, value); err != nil {
+		n, err := fmt.Fprintf(writer, "%s: %s\r\n", key, value)
+		written += n
+		if err != nil {
 			w.errorLogger.Printf("Unable to write response.. %+v", err)
-			return
+			return written, err
 		}
 	}
 
-	// var body string = string(resp.body)
-
-	if _, err := fmt.Fprintf(writer, "\r\n%s", resp.body); err != nil {
+	n, err = fmt.Fprintf(writer, "\r\n%s", resp.body)
+	written += n
+	if err != nil {
 		w.errorLogger.Printf("Unable to write response.. %+v", err)
+		return written, err
 	}
+
+	return written, nil
 }
@@ -3,6 +3,7 @@ package whiskey
 import (
 	"net/http"
 	"slices"
+	"strings"
 )
 
 var configurableHttpMethods []string = []string{
@@ -11,6 +12,7 @@ var configurableHttpMethods []string = []string{
 	http.MethodPut,
 	http.MethodPatch,
 	http.MethodDelete,
+	http.MethodOptions,
 }
 
 // Router handles figuring out which handler to be called for a given request
@@ -35,7 +37,7 @@ func (r *router) addHandler(path string, method string, handlers []HttpHandler)
 		// Since route configuration happens before server is started, panic is fine
 		panic("Invalid HTTP method " + method + " configured")
 	}
-	config := routeConfig{handlers: handlers}
+	config := routeConfig{pattern: path, handlers: handlers}
 	r.routes.insert(path, method, config)
 }
 
@@ -44,6 +46,26 @@ func (r *router) getConfig(path string, method string) (routeConfig, bool) {
 	return config, ok
 }
 
+// getOptionsConfig returns a route answering OPTIONS requests for a path that has routes for other methods but no OPTIONS route of its own
+func (r *router) getOptionsConfig(path string) (routeConfig, bool) {
+	methods, pattern := r.routes.getMethods(path)
+	if len(methods) == 0 {
+		return routeConfig{}, false
+	}
+
+	_, pathParams, _ := r.routes.find(path)
+	allow := strings.Join(append(methods, http.MethodOptions), ", ")
+	return routeConfig{
+		pattern:    pattern,
+		pathParams: pathParams,
+		handlers: []HttpHandler{func(ctx Context) error {
+			ctx.SetHeader(HeaderAllow, allow)
+			ctx.Response().SetStatusCode(http.StatusNoContent)
+			return nil
+		}},
+	}, true
+}
+
 // setGlobalRequestHandler assigns the request handler that gets called if no paths in the server match the incoming path. It's a default request handler
 func (r *router) setGlobalRequestHandler(handler HttpHandler) {
 	r.globalHandlerSet = true
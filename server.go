@@ -1,99 +1,192 @@
 package whiskey
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"slices"
+	"sync/atomic"
 	"time"
 )
 
 // HTTP 1.1 connection handler
 func (w *Whiskey) handleConnection(conn net.Conn) {
+	opened := time.Now()
+	runHooks(w, "OnConnOpen", w.hooks.connOpen, ConnEvent{RemoteAddr: conn.RemoteAddr(), LocalAddr: conn.LocalAddr(), Time: opened})
+
 	defer func(conn net.Conn) {
 		err := conn.Close()
 		if err != nil {
 			w.accessLogger.Println("Error closing connection:", err)
 		}
+
+		closed := time.Now()
+		runHooks(w, "OnConnClose", w.hooks.connClose, ConnEvent{RemoteAddr: conn.RemoteAddr(), LocalAddr: conn.LocalAddr(), Time: closed, Duration: closed.Sub(opened)})
 	}(conn)
 
-	conn.SetReadDeadline(time.Now().Add(w.config.ReadTimeout))
+	readDeadline := time.Now().Add(w.config.ReadTimeout)
+	conn.SetReadDeadline(readDeadline)
 
 	// Read the request
-	req, err := readRequest(conn)
+	req, err := readRequest(conn, w.config.MaxRequestBodySize)
+	start := time.Now()
 	if err != nil {
 		if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
 			w.accessLogger.Println("Connection closed...")
 			return
 		}
 
+		if errors.Is(err, ErrRequestBodyTooLarge) {
+			w.accessLogger.Println("Error reading request:", err)
+			errResp := &HttpResponse{
+				statusCode: http.StatusRequestEntityTooLarge,
+				body:       []byte("request body too large"),
+				headers:    make(map[string]string),
+			}
+			w.respond(conn, errResp, ResponseEvent{Start: start})
+			return
+		}
+
 		w.accessLogger.Println("Error reading request:", err)
 		errResp := &HttpResponse{
 			statusCode: http.StatusBadRequest,
 			body:       []byte("invalid request"),
 			headers:    make(map[string]string),
 		}
-		w.writeResponse(errResp, conn)
+		w.respond(conn, errResp, ResponseEvent{Start: start})
 
 		return
 	}
 
-	w.accessLogger.Printf("Request on path %s", req.path)
-
 	config, validRouteConfig := w.router.getConfig(req.path, req.method)
+	if !validRouteConfig && req.method == http.MethodOptions {
+		config, validRouteConfig = w.router.getOptionsConfig(req.path)
+	}
 	handlers := config.handlers
 	if !validRouteConfig {
-		w.accessLogger.Printf("Handler not found for path %s\n", req.path)
 		globalHandler, ok := w.router.getGlobalRequestHandler()
 		if !ok {
-			w.accessLogger.Println("No handler found for path:", req.path)
-			w.handleNotFound(conn)
-			return
+			globalHandler = notFoundHandler
 		}
 		handlers = []HttpHandler{globalHandler}
 	} else {
 		req.pathParams = config.pathParams
 	}
+	// Global middlewares run before the handlers of every route
+	handlers = append(slices.Clip(w.middlewares), handlers...)
 
 	resp := &HttpResponse{
 		headers: make(map[string]string),
 	}
-	ctx := RequestContext{
-		DataStore: NewDataStore(),
-		request:   req,
-		response:  resp,
+	// The request context is canceled when the read deadline passes, the client goes away or the server is forced to shut down
+	reqCtx, cancel := context.WithDeadline(w.state.context(), readDeadline)
+	defer cancel()
+	// Streamed bodies are read from the connection by the handlers, which notice the client going away when the read fails
+	stopWatching := func() {}
+	if req.bodyReader == nil {
+		stopWatching = watchConnection(conn, cancel)
 	}
 
-	var handlerErr error
-	for _, handler := range handlers {
-		handlerErr = handler(&ctx)
-		if handlerErr != nil {
-			// We break here and let the global error handler take care of handling the error down the line
-			break
-		}
+	ctx := RequestContext{
+		DataStore:  NewDataStore(),
+		request:    req,
+		response:   resp,
+		engine:     w,
+		route:      config.pattern,
+		remoteAddr: addrString(conn.RemoteAddr()),
+		ctx:        reqCtx,
+		handlers:   handlers,
 	}
+	defer func() {
+		if err := ctx.release(); err != nil {
+			w.errorLogger.Println("Error releasing request resources:", err)
+		}
+	}()
+
+	runHooks(w, "OnRequest", w.hooks.request, RequestEvent{Context: &ctx, Time: start})
+
+	// The chain stops at the first error and lets the global error handler take care of handling the error down the line
+	handlerErr := w.runHandlers(&ctx)
 
 	if handlerErr != nil {
-		if err := w.router.errorHandler(handlerErr, ctx); err != nil {
+		runHooks(w, "OnError", w.hooks.error, ErrorEvent{Context: &ctx, Err: handlerErr, Time: time.Now()})
+
+		if err := w.handleError(handlerErr, &ctx); err != nil {
 			w.errorLogger.Println("Error in error handler:", err)
 			// Error handler failed, send a generic error response
 			ctx.String(http.StatusInternalServerError, "Internal Server Error")
 		}
 	}
 
+	stopWatching()
+
+	// Middlewares may have replaced the response
+	resp = ctx.response
+
 	// Default response type of text/plain unless overriden in the handler
-	resp.SetHeader(HeaderContentType, fmt.Sprintf("%s; charset=utf-8", MimeTypeText))
+	if _, ok := resp.headers[HeaderContentType]; !ok {
+		resp.SetHeader(HeaderContentType, fmt.Sprintf("%s; charset=utf-8", MimeTypeText))
+	}
 	resp.SetHeader(HeaderConnection, "close") // Even if the client wants us to keep the connection alive, we close it
 
-	w.writeResponse(resp, conn)
+	w.respond(conn, resp, ResponseEvent{Context: &ctx, Method: req.method, Path: req.path, Route: config.pattern, Headers: req.headers, Start: start})
 }
 
-func (w *Whiskey) handleNotFound(conn net.Conn) {
-	resp := &HttpResponse{
-		headers:    make(map[string]string),
-		statusCode: http.StatusNotFound,
-		body:       []byte("Path route not found"),
+// respond writes resp and runs the OnResponse hooks. event only needs to describe the request, the rest is filled in here
+func (w *Whiskey) respond(conn net.Conn, resp *HttpResponse, event ResponseEvent) {
+	written, err := w.writeResponse(resp, conn)
+	if len(w.hooks.response) == 0 {
+		return
+	}
+
+	event.RemoteAddr = addrString(conn.RemoteAddr())
+	event.Status = resp.StatusCode()
+	event.Bytes = written
+	event.BodyBytes = len(resp.body)
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+	runHooks(w, "OnResponse", w.hooks.response, event)
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
 	}
-	w.writeResponse(resp, conn)
+	return addr.String()
+}
+
+// watchConnection cancels the request context if the client closes the connection while the request is being handled.
+// The returned function stops watching and must be called before the response is written
+func watchConnection(conn net.Conn, cancel context.CancelFunc) func() {
+	var stopped atomic.Bool
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		buf := make([]byte, 1)
+		for {
+			// Connections are closed after every response, so anything the client sends now is ignored. We only care about the read failing
+			if _, err := conn.Read(buf); err != nil {
+				if !stopped.Load() {
+					cancel()
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		stopped.Store(true)
+		// Unblock the pending read
+		conn.SetReadDeadline(time.Now())
+		<-done
+	}
+}
+
+// notFoundHandler answers requests that match no route when no GlobalRequestHandler is set. It runs after the global middlewares like any other handler
+func notFoundHandler(ctx Context) error {
+	return ctx.String(http.StatusNotFound, "Path route not found")
 }
@@ -0,0 +1,333 @@
+package whiskey
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer() *Whiskey {
+	w := New()
+	w.WithAccessLogger(log.New(io.Discard, "", 0))
+	w.WithErrorLogger(log.New(io.Discard, "", 0))
+	return &w
+}
+
+// serve sends a raw request to the server over an in memory connection and returns the raw response
+func serve(t *testing.T, w *Whiskey, request string) string {
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go w.handleConnection(server)
+
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Write([]byte(request)); err != nil {
+		t.Fatalf("failed to write request: %v", err)
+	}
+
+	response, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return string(response)
+}
+
+func TestRequestContextPropagation(t *testing.T) {
+	type ctxKey struct{}
+
+	w := newTestServer()
+	w.GET("/hello", func(ctx Context) error {
+		ctx.WithContext(context.WithValue(ctx.Context(), ctxKey{}, "from middleware"))
+		return nil
+	}, func(ctx Context) error {
+		if _, ok := ctx.Context().Deadline(); !ok {
+			return ctx.String(500, "missing deadline")
+		}
+		value, _ := ctx.Context().Value(ctxKey{}).(string)
+		return ctx.String(200, value)
+	})
+
+	response := serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 200 OK") || !strings.HasSuffix(response, "from middleware") {
+		t.Fatalf("unexpected response %q", response)
+	}
+}
+
+func TestRequestContextCanceledOnDisconnect(t *testing.T) {
+	canceled := make(chan error, 1)
+
+	w := newTestServer()
+	w.GET("/slow", func(ctx Context) error {
+		select {
+		case <-ctx.Context().Done():
+			canceled <- ctx.Context().Err()
+		case <-time.After(2 * time.Second):
+			canceled <- nil
+		}
+		return nil
+	})
+
+	client, server := net.Pipe()
+	go w.handleConnection(server)
+
+	client.Write([]byte("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	client.Close()
+
+	if err := <-canceled; err != context.Canceled {
+		t.Fatalf("expected request context to be canceled, got %v", err)
+	}
+}
+
+func TestRequestContextCanceledOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+
+	w := newTestServer()
+	w.GET("/slow", func(ctx Context) error {
+		close(started)
+		<-ctx.Context().Done()
+		canceled <- ctx.Context().Err()
+		return nil
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+	w.state.conns.Add(1)
+	go func() {
+		defer w.state.conns.Done()
+		w.handleConnection(server)
+	}()
+	go io.Copy(io.Discard, client)
+
+	client.Write([]byte("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	<-started
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := w.Shutdown(shutdownCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected shutdown to time out waiting for the request, got %v", err)
+	}
+
+	if err := <-canceled; err != context.Canceled {
+		t.Fatalf("expected request context to be canceled, got %v", err)
+	}
+}
+
+func TestRunAfterShutdown(t *testing.T) {
+	started := make(chan net.Addr, 1)
+
+	w := newTestServer()
+	w.config.Addr = "127.0.0.1"
+	w.config.Port = 0
+	w.OnStart(func(e StartEvent) { started <- e.Addr })
+	w.GET("/ping", func(ctx Context) error {
+		// Requests of a new Run must not inherit the canceled context of the previous one
+		if err := ctx.Context().Err(); err != nil {
+			return err
+		}
+		return ctx.String(200, "pong")
+	})
+
+	for run := range 2 {
+		stopped := make(chan struct{})
+		go func() {
+			w.Run()
+			close(stopped)
+		}()
+
+		conn, err := net.Dial("tcp", (<-started).String())
+		if err != nil {
+			t.Fatalf("run %d: failed to connect: %v", run, err)
+		}
+		conn.Write([]byte("GET /ping HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		response, _ := io.ReadAll(conn)
+		conn.Close()
+		if !strings.HasSuffix(string(response), "pong") {
+			t.Fatalf("run %d: expected the request to be served, got %q", run, response)
+		}
+
+		if err := w.Shutdown(context.Background()); err != nil {
+			t.Fatalf("run %d: unexpected shutdown error: %v", run, err)
+		}
+		<-stopped
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	lateWrite := make(chan struct{})
+
+	w := newTestServer()
+	w.GET("/slow", Timeout(20*time.Millisecond), func(ctx Context) error {
+		<-ctx.Context().Done()
+		// Writes after the timeout must not reach the client or race with the error response
+		ctx.SetHeader("X-Late", "true")
+		ctx.String(200, "too late")
+		ctx.Set("late", true)
+		close(lateWrite)
+		return nil
+	})
+	w.GET("/fast", Timeout(time.Second), func(ctx Context) error {
+		ctx.Set("user", "john")
+		return nil
+	}, func(ctx Context) error {
+		user, _ := ctx.GetString("user")
+		return ctx.String(201, "hello "+user)
+	})
+
+	custom := NewHTTPErrorWithMessage(http.StatusGatewayTimeout, "upstream too slow", BodyTypeString)
+	w.GET("/custom", TimeoutWithConfig(TimeoutConfig{Duration: 10 * time.Millisecond, Error: custom}), func(ctx Context) error {
+		<-ctx.Context().Done()
+		return nil
+	})
+
+	response := serve(t, w, "GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 503 Service Unavailable") || strings.Contains(response, "too late") || strings.Contains(response, "X-Late") {
+		t.Fatalf("expected 503 without late writes, got %q", response)
+	}
+	<-lateWrite
+
+	response = serve(t, w, "GET /fast HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 201 Created") || !strings.HasSuffix(response, "hello john") {
+		t.Fatalf("expected handlers to finish in time, got %q", response)
+	}
+
+	response = serve(t, w, "GET /custom HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 504 Gateway Timeout") {
+		t.Fatalf("expected custom timeout error, got %q", response)
+	}
+}
+
+func TestGlobalMiddleware(t *testing.T) {
+	w := newTestServer()
+	w.Use(func(ctx Context) error {
+		ctx.SetHeader("X-Global", "true")
+		return nil
+	}, Timeout(10*time.Millisecond))
+	w.GET("/slow", func(ctx Context) error {
+		<-ctx.Context().Done()
+		return nil
+	})
+
+	response := serve(t, w, "GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 503 Service Unavailable") || !strings.Contains(response, "X-Global: true") {
+		t.Fatalf("expected global middlewares to run, got %q", response)
+	}
+}
+
+func TestGlobalMiddlewareOnNotFound(t *testing.T) {
+	w := newTestServer()
+	w.Use(func(ctx Context) error {
+		ctx.SetHeader("X-Global", "true")
+		return nil
+	})
+
+	response := serve(t, w, "GET /missing HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 404 Not Found") || !strings.Contains(response, "X-Global: true") || !strings.HasSuffix(response, "Path route not found") {
+		t.Fatalf("expected global middlewares to run for unknown paths, got %q", response)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	var logs strings.Builder
+	w := newTestServer()
+	w.WithErrorLogger(log.New(&logs, "", 0))
+	w.GET("/panic", func(ctx Context) error {
+		panic("something broke")
+	})
+	w.GET("/timeout-panic", Timeout(time.Second), func(ctx Context) error {
+		panic("broke on another goroutine")
+	})
+
+	response := serve(t, w, "GET /panic HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 500 Internal Server Error") || strings.Contains(response, "something broke") {
+		t.Fatalf("expected generic 500, got %q", response)
+	}
+	if !strings.Contains(logs.String(), "Recovered from panic: something broke") || !strings.Contains(logs.String(), "recovery.go") {
+		t.Fatalf("expected panic and stack trace to be logged, got %q", logs.String())
+	}
+
+	response = serve(t, w, "GET /timeout-panic HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 500 Internal Server Error") {
+		t.Fatalf("expected panic in timeout middleware to be recovered, got %q", response)
+	}
+
+	config := defaultConfig
+	config.Debug = true
+	w.WithConfig(config)
+	response = serve(t, w, "GET /panic HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.Contains(response, "panic: something broke") || !strings.Contains(response, "goroutine") {
+		t.Fatalf("expected stack trace in debug mode, got %q", response)
+	}
+}
+
+func TestPanicReachesErrorHandler(t *testing.T) {
+	var received error
+	w := newTestServer()
+	w.GET("/panic", func(ctx Context) error {
+		panic(io.ErrUnexpectedEOF)
+	})
+	w.GlobalErrorHandler(func(err error, ctx Context) error {
+		received = err
+		return ctx.String(http.StatusTeapot, "handled")
+	})
+
+	response := serve(t, w, "GET /panic HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if !strings.HasPrefix(response, "HTTP/1.1 418") {
+		t.Fatalf("expected error handler response, got %q", response)
+	}
+
+	var panicErr *PanicError
+	if !errors.As(received, &panicErr) || !errors.Is(received, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected *PanicError wrapping the panic value, got %v", received)
+	}
+}
+
+func TestResponseInspection(t *testing.T) {
+	w := newTestServer()
+
+	// Computes an ETag from whatever the handler wrote
+	etag := func(ctx Context) error {
+		if err := ctx.Next(); err != nil {
+			return err
+		}
+		if ctx.Status() == http.StatusOK && ctx.ResponseSize() > 0 {
+			ctx.ResponseHeaders()["ETag"] = fmt.Sprintf(`"%x"`, sha256.Sum256(ctx.Response().Body()))
+		}
+		return nil
+	}
+
+	// Captures the response of the handler and rewrites it into the original one
+	shout := func(ctx Context) error {
+		original := ctx.Response()
+		captured := NewHttpResponse()
+		ctx.SetResponse(captured)
+		err := ctx.Next()
+		ctx.SetResponse(original)
+
+		maps.Copy(original.Headers(), captured.Headers())
+		original.SetStatusCode(captured.StatusCode())
+		original.Send(bytes.ToUpper(captured.Body()))
+		return err
+	}
+
+	w.GET("/hello", etag, shout, func(ctx Context) error {
+		return ctx.String(http.StatusOK, "hello")
+	})
+
+	response := serve(t, w, "GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	expectedETag := fmt.Sprintf(`ETag: "%x"`, sha256.Sum256([]byte("HELLO")))
+	if !strings.HasSuffix(response, "\r\n\r\nHELLO") || !strings.Contains(response, expectedETag) {
+		t.Fatalf("expected rewritten body with ETag, got %q", response)
+	}
+}
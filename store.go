@@ -1,6 +1,9 @@
 package whiskey
 
-import "reflect"
+import (
+	"maps"
+	"reflect"
+)
 
 type Type int
 
@@ -9,6 +12,7 @@ const (
 	TypeInt
 	TypeFloat
 	TypeBool
+	TypeAny // Any other value, like structs, maps or pointers. Read it back with Get
 )
 
 type Value struct {
@@ -26,7 +30,23 @@ func NewDataStore() *DataStore {
 		data: make(map[string]Value),
 	}
 }
+
+// clone returns a copy of the store whose keys can be changed without affecting the original
+func (ds *DataStore) clone() *DataStore {
+	if ds == nil {
+		return nil
+	}
+	return &DataStore{
+		data: maps.Clone(ds.data),
+	}
+}
+
 func (ds *DataStore) Set(key string, value any) {
+	if value == nil {
+		ds.data[key] = Value{value: nil, dataType: TypeAny}
+		return
+	}
+
 	dataType := reflect.TypeOf(value)
 	switch dataType.Kind() {
 	case reflect.String:
@@ -37,6 +57,8 @@ func (ds *DataStore) Set(key string, value any) {
 		ds.data[key] = Value{value: value, dataType: TypeFloat}
 	case reflect.Bool:
 		ds.data[key] = Value{value: value, dataType: TypeBool}
+	default:
+		ds.data[key] = Value{value: value, dataType: TypeAny}
 	}
 }
 
@@ -71,3 +71,21 @@ func TestDataStore(t *testing.T) {
 		t.Errorf("Expected 'newValue', got '%v'", val)
 	}
 }
+
+func TestDataStoreAnyValue(t *testing.T) {
+	ds := NewDataStore()
+	ds.Set("claims", map[string]any{"sub": "user-1"})
+	ds.Set("nothing", nil)
+
+	value, ok := ds.Get("claims")
+	claims, isMap := value.(map[string]any)
+	if !ok || !isMap || claims["sub"] != "user-1" {
+		t.Errorf("expected the map to be stored, got %v", value)
+	}
+	if _, ok := ds.GetString("claims"); ok {
+		t.Error("expected GetString to ignore non string values")
+	}
+	if value, ok := ds.Get("nothing"); !ok || value != nil {
+		t.Errorf("expected nil to be stored, got %v", value)
+	}
+}
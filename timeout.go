@@ -0,0 +1,115 @@
+package whiskey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig configures the Timeout middleware
+type TimeoutConfig struct {
+	Duration time.Duration // Maximum time the handlers after the middleware get to run
+	Error    error         // Returned to the global error handler when the handlers time out. Defaults to a 503 Service Unavailable HttpError
+}
+
+// Timeout returns a middleware that cancels the request context after duration and responds with 503 Service Unavailable if the handlers after it haven't finished by then
+func Timeout(duration time.Duration) HttpHandler {
+	return TimeoutWithConfig(TimeoutConfig{Duration: duration})
+}
+
+// TimeoutWithConfig returns a Timeout middleware with custom configuration.
+// The handlers after the middleware run on their own goroutine with a copy of the context. If they time out, anything they write afterwards goes to that copy and is discarded.
+// Their multipart form is removed and a panic is logged once they finish
+func TimeoutWithConfig(config TimeoutConfig) HttpHandler {
+	if config.Error == nil {
+		config.Error = NewHTTPErrorWithMessage(http.StatusServiceUnavailable, "request timed out", BodyTypeJSON)
+	}
+
+	return func(ctx Context) error {
+		reqCtx, ok := ctx.(*RequestContext)
+		if !ok {
+			return errors.New("timeout middleware requires a *RequestContext")
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(reqCtx.Context(), config.Duration)
+		defer cancel()
+
+		forked := reqCtx.fork(timeoutCtx)
+		finished := make(chan timeoutResult, 1)
+
+		// Once the request has timed out, the goroutine owns the resources of the forked context
+		var mu sync.Mutex
+		abandoned := false
+
+		go func() {
+			var result timeoutResult
+			defer func() {
+				if p := recover(); p != nil {
+					result.panicErr = newPanicError(p)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				if !abandoned {
+					finished <- result
+					return
+				}
+				forked.releaseAbandoned(result.panicErr)
+			}()
+			result.err = forked.Next()
+		}()
+
+		select {
+		case result := <-finished:
+			return reqCtx.joinTimeoutResult(forked, result)
+		case <-timeoutCtx.Done():
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		// The multipart form may still be read by the handlers, they remove it once they are done
+		reqCtx.multipartForm = nil
+		select {
+		case result := <-finished:
+			// The handlers finished right as the deadline passed, their results are discarded all the same
+			forked.releaseAbandoned(result.panicErr)
+		default:
+			abandoned = true
+		}
+
+		reqCtx.Abort()
+		return config.Error
+	}
+}
+
+// timeoutResult is how the handlers running after the Timeout middleware finished
+type timeoutResult struct {
+	err      error
+	panicErr *PanicError
+}
+
+func (r *RequestContext) joinTimeoutResult(forked *RequestContext, result timeoutResult) error {
+	if result.panicErr != nil {
+		// Re-panic on the connection goroutine so it's handled like any other panic in the chain
+		panic(result.panicErr)
+	}
+	r.join(forked)
+	return result.err
+}
+
+// releaseAbandoned frees the resources of a forked context whose handlers finished after the request timed out.
+// The request has already failed with the timeout error, so a panic can only be logged
+func (r *RequestContext) releaseAbandoned(panicErr *PanicError) {
+	if r.engine == nil {
+		r.release()
+		return
+	}
+	if panicErr != nil {
+		r.engine.errorLogger.Printf("Recovered from panic after timeout: %v\n%s", panicErr.Value, panicErr.Stack)
+	}
+	if err := r.release(); err != nil {
+		r.engine.errorLogger.Println("Error releasing request resources:", err)
+	}
+}
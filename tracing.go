@@ -0,0 +1,381 @@
+package whiskey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace across every service taking part in it
+type TraceID [16]byte
+
+// SpanID identifies a single span of a trace
+type SpanID [8]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether the trace ID isn't all zeroes, which W3C Trace Context forbids
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid reports whether the span ID isn't all zeroes, which W3C Trace Context forbids
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SpanContext is the part of a span propagated to other services in the traceparent and tracestate headers
+type SpanContext struct {
+	TraceID    TraceID `json:"trace_id"`
+	SpanID     SpanID  `json:"span_id"`
+	Sampled    bool    `json:"sampled"`
+	TraceState string  `json:"trace_state,omitempty"` // Vendor specific data, passed on as is
+}
+
+// IsValid reports whether the span context has both a trace and a span ID
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// TraceParent formats the span context as a version 00 traceparent header value
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// parseTraceParent parses a traceparent header value as described by W3C Trace Context. Versions after 00 are parsed by their 00 prefix as the spec requires
+func parseTraceParent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || (len(value) > 55 && value[55] != '-') {
+		return sc, false
+	}
+
+	version, traceID, spanID, flags := value[0:2], value[3:35], value[36:52], value[53:55]
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, false
+	}
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(value) != 55) {
+		return sc, false
+	}
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return sc, false
+	}
+
+	hex.Decode(sc.TraceID[:], []byte(traceID))
+	hex.Decode(sc.SpanID[:], []byte(spanID))
+	var flagByte [1]byte
+	hex.Decode(flagByte[:], []byte(flags))
+	sc.Sampled = flagByte[0]&0x01 == 0x01
+
+	return sc, sc.IsValid()
+}
+
+// validTraceState accepts a tracestate header value within the limits of W3C Trace Context. Invalid values are dropped rather than propagated
+func validTraceState(value string) bool {
+	if value == "" || len(value) > 512 {
+		return false
+	}
+	members := strings.Split(value, ",")
+	if len(members) > 32 {
+		return false
+	}
+	for _, member := range members {
+		key, val, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok || key == "" || val == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Span records a unit of work. The server starts one for every routed request
+type Span struct {
+	mu         sync.Mutex
+	name       string
+	context    SpanContext
+	parent     SpanContext
+	start      time.Time
+	end        time.Time
+	attributes map[string]any
+	err        string
+	ended      bool
+}
+
+// SpanData is a snapshot of a finished span, as received by a SpanExporter
+type SpanData struct {
+	Name       string         `json:"name"`
+	Context    SpanContext    `json:"context"`
+	Parent     SpanContext    `json:"parent"` // Zero if the span started a new trace
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	Duration   time.Duration  `json:"duration"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Error      string         `json:"error,omitempty"` // Set if the span failed
+}
+
+func newSpan(name string, parent SpanContext, start time.Time) *Span {
+	sc := SpanContext{
+		TraceID:    parent.TraceID,
+		Sampled:    true, // New traces are always sampled. Continued traces keep the decision of the caller
+		TraceState: parent.TraceState,
+	}
+	if parent.IsValid() {
+		sc.Sampled = parent.Sampled
+	} else {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+
+	return &Span{
+		name:       name,
+		context:    sc,
+		parent:     parent,
+		start:      start,
+		attributes: make(map[string]any),
+	}
+}
+
+// SpanContext returns the IDs of the span, to be propagated to other services
+func (s *Span) SpanContext() SpanContext {
+	return s.context
+}
+
+// SetName replaces the name of the span
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// SetAttribute sets an attribute of the span, replacing any existing value. Values should be strings, numbers or booleans
+func (s *Span) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// RecordError marks the span as failed with err
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// failed marks the span as failed with message unless an error was already recorded
+func (s *Span) failed(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == "" {
+		s.err = message
+	}
+}
+
+// Inject sets the traceparent and tracestate headers of an outgoing request, so the service it calls continues the trace
+func (s *Span) Inject(header http.Header) {
+	header.Set(HeaderTraceParent, s.context.TraceParent())
+	if s.context.TraceState != "" {
+		header.Set(HeaderTraceState, s.context.TraceState)
+	}
+}
+
+// finish ends the span and returns its data. It reports false if the span already ended
+func (s *Span) finish(end time.Time) (SpanData, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return SpanData{}, false
+	}
+	s.ended = true
+	s.end = end
+
+	return SpanData{
+		Name:       s.name,
+		Context:    s.context,
+		Parent:     s.parent,
+		Start:      s.start,
+		End:        s.end,
+		Duration:   s.end.Sub(s.start),
+		Attributes: maps.Clone(s.attributes),
+		Error:      s.err,
+	}, true
+}
+
+type spanContextKey struct{}
+
+// SpanFromContext returns the span of the request the context belongs to, or nil if tracing isn't enabled
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SpanExporter receives every sampled span once it has ended. It's called on the connection's goroutine, so slow exporters should batch and send in the background
+type SpanExporter interface {
+	ExportSpan(span SpanData) error
+}
+
+// JSONExporter writes every span as a line of JSON
+type JSONExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONExporter returns an exporter writing to out, os.Stdout if nil
+func NewJSONExporter(out io.Writer) *JSONExporter {
+	if out == nil {
+		out = os.Stdout
+	}
+	return &JSONExporter{encoder: json.NewEncoder(out)}
+}
+
+func (e *JSONExporter) ExportSpan(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoder.Encode(span)
+}
+
+// InMemoryExporter keeps every span in memory. It's meant for tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *InMemoryExporter) ExportSpan(span SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+// Spans returns the exported spans in the order they ended
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset removes every exported span
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// TracingConfig configures Whiskey.Tracing
+type TracingConfig struct {
+	Exporter SpanExporter // Receives the spans of sampled requests. Defaults to a JSONExporter writing to os.Stdout
+}
+
+// Tracing starts a server span for every routed request. The trace of the caller is continued if the request carries a valid traceparent header.
+// The span is available through Context.Span and SpanFromContext, and is exported with the route, status and timing of the request once the response is written
+func (w *Whiskey) Tracing(config TracingConfig) {
+	if config.Exporter == nil {
+		config.Exporter = NewJSONExporter(nil)
+	}
+
+	w.OnRequest(func(e RequestEvent) {
+		reqCtx, ok := e.Context.(*RequestContext)
+		if !ok {
+			return
+		}
+
+		var parent SpanContext
+		if traceParent, ok := reqCtx.GetHeader(HeaderTraceParent); ok {
+			if sc, ok := parseTraceParent(traceParent); ok {
+				parent = sc
+				if traceState, ok := reqCtx.GetHeader(HeaderTraceState); ok && validTraceState(traceState) {
+					parent.TraceState = traceState
+				}
+			}
+		}
+
+		name := reqCtx.Method()
+		if reqCtx.Route() != "" {
+			name += " " + reqCtx.Route()
+		}
+
+		span := newSpan(name, parent, e.Time)
+		span.SetAttribute("http.request.method", reqCtx.Method())
+		span.SetAttribute("url.path", reqCtx.URL())
+		span.SetAttribute("http.route", reqCtx.Route())
+		span.SetAttribute("client.address", reqCtx.RemoteAddr())
+		if userAgent, ok := reqCtx.GetHeader(HeaderUserAgent); ok {
+			span.SetAttribute("user_agent.original", userAgent)
+		}
+
+		reqCtx.span = span
+		reqCtx.WithContext(context.WithValue(reqCtx.Context(), spanContextKey{}, span))
+	})
+
+	w.OnError(func(e ErrorEvent) {
+		if span := e.Context.Span(); span != nil {
+			span.RecordError(e.Err)
+		}
+	})
+
+	w.OnResponse(func(e ResponseEvent) {
+		if e.Context == nil {
+			return
+		}
+		span := e.Context.Span()
+		if span == nil {
+			return
+		}
+
+		span.SetAttribute("http.response.status_code", e.Status)
+		span.SetAttribute("http.response.body.size", e.BodyBytes)
+		if e.Err != nil {
+			span.RecordError(e.Err)
+		}
+		// Server errors fail the span even if they were handled, client errors are the client's
+		if e.Status >= http.StatusInternalServerError {
+			span.failed(http.StatusText(e.Status))
+		}
+
+		data, ok := span.finish(e.Start.Add(e.Duration))
+		if !ok || !data.Context.Sampled {
+			return
+		}
+		if err := config.Exporter.ExportSpan(data); err != nil {
+			w.errorLogger.Println("Error exporting span:", err)
+		}
+	})
+}
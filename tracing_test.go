@@ -0,0 +1,160 @@
+package whiskey
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{name: "sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true, sampled: true},
+		{name: "not sampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true},
+		{name: "future version with extra fields", value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true, sampled: true},
+		{name: "version 00 with extra fields", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "forbidden version", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "zero trace id", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero span id", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "uppercase hex", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "too short", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := parseTraceParent(tt.value)
+			if ok != tt.valid {
+				t.Fatalf("expected valid to be %v, got %v", tt.valid, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("unexpected ids %s %s", sc.TraceID, sc.SpanID)
+			}
+			if sc.Sampled != tt.sampled {
+				t.Errorf("expected sampled to be %v", tt.sampled)
+			}
+		})
+	}
+}
+
+func TestTracingContinuesTrace(t *testing.T) {
+	exporter := &InMemoryExporter{}
+
+	w := newTestServer()
+	w.Tracing(TracingConfig{Exporter: exporter})
+
+	var outgoing http.Header
+	w.GET("/users/{id}", func(ctx Context) error {
+		span := ctx.Span()
+		if span == nil || SpanFromContext(ctx.Context()) != span {
+			return ctx.String(500, "missing span")
+		}
+		span.SetAttribute("user.id", "42")
+
+		outgoing = http.Header{}
+		span.Inject(outgoing)
+		return ctx.String(200, "user")
+	})
+
+	serve(t, w, "GET /users/42 HTTP/1.1\r\nHost: localhost\r\n"+
+		"traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01\r\n"+
+		"tracestate: vendor=abc\r\n\r\n")
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "GET /users/{id}" {
+		t.Errorf("unexpected span name %q", span.Name)
+	}
+	if span.Context.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the span to continue the incoming trace, got %+v", span)
+	}
+	if span.Context.SpanID == span.Parent.SpanID {
+		t.Error("expected the span to get its own id")
+	}
+
+	expected := map[string]any{
+		"http.request.method":       "GET",
+		"url.path":                  "/users/42",
+		"http.route":                "/users/{id}",
+		"http.response.status_code": 200,
+		"user.id":                   "42",
+	}
+	for key, value := range expected {
+		if span.Attributes[key] != value {
+			t.Errorf("expected attribute %s to be %v, got %v", key, value, span.Attributes[key])
+		}
+	}
+	if span.Duration <= 0 || span.End.Before(span.Start) || span.Error != "" {
+		t.Errorf("unexpected timing or error %+v", span)
+	}
+
+	if outgoing.Get(HeaderTraceParent) != span.Context.TraceParent() || outgoing.Get(HeaderTraceState) != "vendor=abc" {
+		t.Errorf("expected the span to be injected in outgoing headers, got %v", outgoing)
+	}
+}
+
+func TestTracingNewTraceAndErrors(t *testing.T) {
+	exporter := &InMemoryExporter{}
+
+	w := newTestServer()
+	w.Tracing(TracingConfig{Exporter: exporter})
+	w.GET("/fail", func(ctx Context) error {
+		return errors.New("database unavailable")
+	})
+	w.GET("/unsampled", func(ctx Context) error {
+		return ctx.String(200, "ok")
+	})
+
+	serve(t, w, "GET /fail HTTP/1.1\r\nHost: localhost\r\ntraceparent: invalid\r\n\r\n")
+	serve(t, w, "GET /unsampled HTTP/1.1\r\nHost: localhost\r\n"+
+		"traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00\r\n\r\n")
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected only the sampled span to be exported, got %d", len(spans))
+	}
+	if !spans[0].Context.TraceID.IsValid() || spans[0].Parent.IsValid() {
+		t.Errorf("expected a new trace, got %+v", spans[0])
+	}
+	if spans[0].Error != "database unavailable" || spans[0].Attributes["http.response.status_code"] != 500 {
+		t.Errorf("expected the handler error on the span, got %+v", spans[0])
+	}
+
+	exporter.Reset()
+	if len(exporter.Spans()) != 0 {
+		t.Error("expected Reset to remove every span")
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	var out bytes.Buffer
+	exporter := NewJSONExporter(&out)
+
+	span := newSpan("GET /", SpanContext{}, time.Now())
+	data, _ := span.finish(time.Now())
+	if err := exporter.ExportSpan(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", out.String(), err)
+	}
+	context, _ := decoded["context"].(map[string]any)
+	if context["trace_id"] != data.Context.TraceID.String() || decoded["name"] != "GET /" {
+		t.Errorf("unexpected JSON span %s", out.String())
+	}
+}
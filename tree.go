@@ -2,6 +2,7 @@ package whiskey
 
 import (
 	"errors"
+	"slices"
 	"strings"
 )
 
@@ -14,6 +15,7 @@ type routeTree struct {
 }
 
 type routeConfig struct {
+	pattern    string // The path the route was registered with
 	pathParams map[string]string
 	handlers   []HttpHandler
 }
@@ -108,10 +110,45 @@ func (t *routeTree) insert(path string, method string, config routeConfig) error
 // getConfig returns the appropriate
 func (t *routeTree) getConfig(path string, method string) (routeConfig, bool) {
 	var empty routeConfig
-	if path == "" {
+	current, pathParams, ok := t.find(path)
+	if !ok {
 		return empty, false
 	}
 
+	config, ok := current.handlers[method]
+	if !ok {
+		return empty, ok
+	}
+
+	config.pathParams = pathParams
+
+	return config, true
+}
+
+// getMethods returns the methods registered for the route matching path, along with the pattern of the route
+func (t *routeTree) getMethods(path string) ([]string, string) {
+	current, _, ok := t.find(path)
+	if !ok {
+		return nil, ""
+	}
+
+	var methods []string
+	var pattern string
+	for method, config := range current.handlers {
+		methods = append(methods, method)
+		pattern = config.pattern
+	}
+	slices.Sort(methods)
+
+	return methods, pattern
+}
+
+// find returns the node of the route matching path and the path params extracted along the way
+func (t *routeTree) find(path string) (*node, map[string]string, bool) {
+	if path == "" {
+		return nil, nil, false
+	}
+
 	trimmedPath := strings.TrimPrefix(strings.TrimSuffix(path, "/"), "/")
 	pathParts := strings.Split(trimmedPath, "/")
 
@@ -121,7 +158,7 @@ func (t *routeTree) getConfig(path string, method string) (routeConfig, bool) {
 	if !ok {
 		current, ok = t.findPathParam(t.roots)
 		if !ok {
-			return empty, false
+			return nil, nil, false
 		}
 		pathParams[extractParam(current.key)] = pathParts[0]
 	}
@@ -133,7 +170,7 @@ func (t *routeTree) getConfig(path string, method string) (routeConfig, bool) {
 			// if there's no exact match, maybe it's a path param
 			current, ok = t.findPathParam(children)
 			if !ok {
-				return empty, false
+				return nil, nil, false
 			}
 
 			pathParams[extractParam(current.key)] = pathParts[idx]
@@ -141,17 +178,10 @@ func (t *routeTree) getConfig(path string, method string) (routeConfig, bool) {
 	}
 
 	if !current.end {
-		return empty, false
+		return nil, nil, false
 	}
 
-	config, ok := current.handlers[method]
-	if !ok {
-		return empty, ok
-	}
-
-	config.pathParams = pathParams
-
-	return config, true
+	return current, pathParams, true
 }
 
 func (t *routeTree) findPathParam(nodes map[string]*node) (*node, bool) {
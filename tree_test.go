@@ -183,7 +183,7 @@ func TestMultipleRoutes(t *testing.T) {
 			if found {
 				// Execute the handler to verify it's the correct one
 				mockContext := RequestContext{}
-				err := config.handlers[0](mockContext)
+				err := config.handlers[0](&mockContext)
 				if err.Error() != tc.handlerID {
 					t.Errorf("Expected handler %s, got %s", tc.handlerID, err.Error())
 				}
@@ -224,7 +224,7 @@ func TestNestedRoutes(t *testing.T) {
 			if found {
 				// Execute the handler to verify it's the correct one
 				mockContext := RequestContext{}
-				err := config.handlers[0](mockContext)
+				err := config.handlers[0](&mockContext)
 				if err.Error() != tc.handlerID {
 					t.Errorf("Expected handler %s, got %s", tc.handlerID, err.Error())
 				}
@@ -324,7 +324,7 @@ func TestSlashHandling(t *testing.T) {
 			if found {
 				// Execute the handler to verify it's the correct one
 				mockContext := RequestContext{}
-				err := config.handlers[0](mockContext)
+				err := config.handlers[0](&mockContext)
 				if err.Error() != tc.handlerID {
 					t.Errorf("Expected handler %s, got %s", tc.handlerID, err.Error())
 				}
@@ -352,8 +352,8 @@ func TestEdgeCases(t *testing.T) {
 
 		// Verify they're different handlers
 		mockContext := RequestContext{}
-		getErr := getConfig.handlers[0](mockContext)
-		postErr := postConfig.handlers[0](mockContext)
+		getErr := getConfig.handlers[0](&mockContext)
+		postErr := postConfig.handlers[0](&mockContext)
 
 		if getErr.Error() == postErr.Error() {
 			t.Errorf("Expected different handlers, got the same: %s", getErr.Error())
@@ -376,7 +376,7 @@ func TestEdgeCases(t *testing.T) {
 
 		// Verify it's the correct handler
 		mockContext := RequestContext{}
-		err := config.handlers[0](mockContext)
+		err := config.handlers[0](&mockContext)
 		if err.Error() != "handler one called" {
 			t.Errorf("Expected 'handler one called', got %s", err.Error())
 		}
@@ -408,7 +408,7 @@ func TestCaseSensitivity(t *testing.T) {
 			}
 
 			mockContext := RequestContext{}
-			err := config.handlers[0](mockContext)
+			err := config.handlers[0](&mockContext)
 			if err.Error() != tc.handlerID {
 				t.Errorf("Expected handler %s, got %s", tc.handlerID, err.Error())
 			}
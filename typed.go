@@ -0,0 +1,37 @@
+package whiskey
+
+import "net/http"
+
+// StatusCoder can be implemented by responses of typed handlers to pick the status code they are sent with. Responses without it are sent with 200
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// TypedHandler handles a request bound into Req and returns the response to send
+type TypedHandler[Req any, Resp any] func(ctx Context, req Req) (Resp, error)
+
+// Typed adapts a TypedHandler into an HttpHandler. The request is filled with ctx.Bind, so Req must be a struct using the usual binding and `validate` tags.
+// Binding, validation and handler errors are returned as is and reach the global error handler, the response is encoded with ctx.Negotiate
+//
+//	server.POST("/users/{orgId}", whiskey.Typed(func(ctx whiskey.Context, req CreateUser) (UserResp, error) {
+//		return UserResp{Id: "123", Name: req.Name}, nil
+//	}))
+func Typed[Req any, Resp any](handler TypedHandler[Req, Resp]) HttpHandler {
+	return func(ctx Context) error {
+		var req Req
+		if err := ctx.Bind(&req); err != nil {
+			return err
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		statusCode := http.StatusOK
+		if coder, ok := any(resp).(StatusCoder); ok {
+			statusCode = coder.StatusCode()
+		}
+		return ctx.Negotiate(statusCode, resp)
+	}
+}
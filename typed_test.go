@@ -0,0 +1,72 @@
+package whiskey
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type createUserReq struct {
+	OrgId string `path:"orgId"`
+	Name  string `json:"name" validate:"required"`
+}
+
+type userResp struct {
+	OrgId string `json:"orgId"`
+	Name  string `json:"name"`
+}
+
+func (userResp) StatusCode() int {
+	return http.StatusCreated
+}
+
+func TestTyped(t *testing.T) {
+	errConflict := NewHttpError(http.StatusConflict, BodyTypeJSON)
+	handler := Typed(func(ctx Context, req createUserReq) (userResp, error) {
+		if req.Name == "taken" {
+			return userResp{}, errConflict
+		}
+		return userResp{OrgId: req.OrgId, Name: req.Name}, nil
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantErr    int
+	}{
+		{name: "Binds, handles and encodes", body: `{"name": "john"}`, wantStatus: http.StatusCreated, wantBody: `{"orgId":"acme","name":"john"}`},
+		{name: "Validation error", body: `{}`, wantErr: http.StatusUnprocessableEntity},
+		{name: "Handler error", body: `{"name": "taken"}`, wantErr: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := RequestContext{
+				request: HttpRequest{
+					pathParams: map[string]string{"orgId": "acme"},
+					headers:    map[string]string{HeaderContentType: MimeTypeJSON, HeaderAccept: MimeTypeJSON},
+					body:       []byte(tt.body),
+				},
+				response: &HttpResponse{},
+			}
+
+			err := handler(&ctx)
+			if tt.wantErr != 0 {
+				var httpErr HttpError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantErr {
+					t.Fatalf("expected HttpError with status %d, got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if ctx.response.statusCode != tt.wantStatus || string(ctx.response.body) != tt.wantBody {
+				t.Fatalf("expected %d %s, got %d %s", tt.wantStatus, tt.wantBody, ctx.response.statusCode, ctx.response.body)
+			}
+		})
+	}
+}
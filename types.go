@@ -2,7 +2,12 @@ package whiskey
 
 import (
 	"bytes"
+	"io"
 	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -17,9 +22,13 @@ type ServerConfig struct {
 	Addr               string
 	MaxConcurrency     int
 	MaxHeaderBytes     int
-	MaxRequestBodySize int64
-	ReadTimeout        time.Duration
+	MaxRequestBodySize int64         // Requests with a higher Content-Length are rejected with 413 Request Entity Too Large. Bodies other than multipart/form-data are read into memory whole. 0 disables the limit
+	MaxMultipartMemory int64         // Bytes of uploaded file content MultipartForm keeps in memory per request. Files above this are spooled from the connection to temporary files
+	MaxMultipartParts  int           // Maximum number of parts in a multipart/form-data body. 0 disables the limit
+	ReadTimeout        time.Duration // Deadline for reading the request. It is also the deadline of the request context seen by handlers
 	WriteTimeout       time.Duration
+	Debug              bool // Includes panic values and stack traces in the responses of the default error handler. Never enable it in production
+	ProblemDetails     bool // Makes the default error handler send every error as RFC 9457 application/problem+json
 }
 
 type RunOpts struct{}
@@ -28,17 +37,51 @@ type HttpRequest struct {
 	method      string
 	path        string
 	body        []byte
+	bodyReader  io.Reader // Reads multipart/form-data bodies from the connection as the handlers consume them. body is empty for those requests
 	headers     map[string]string
-	queryParams map[string]string
+	queryParams map[string]string   // First value of every query key
+	query       map[string][]string // Every value of every query key, in the order they were sent
 	pathParams  map[string]string
 }
 
+// getHeader looks up a request header ignoring the case of the key, since clients are free to send header names in any case
+func (h HttpRequest) getHeader(key string) (string, bool) {
+	if value, ok := h.headers[key]; ok {
+		return value, true
+	}
+	for k, value := range h.headers {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// hasBody reports whether the request was sent with a body, buffered or streamed
+func (h HttpRequest) hasBody() bool {
+	return len(h.body) > 0 || h.bodyReader != nil
+}
+
+// mediaType returns the media type of the request body without parameters like charset
+func (h HttpRequest) mediaType() string {
+	contentType, ok := h.getHeader(HeaderContentType)
+	if !ok {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
 func (h HttpRequest) Equal(other HttpRequest) bool {
 	return h.method == other.method &&
 		h.path == other.path &&
 		bytes.Equal(h.body, other.body) &&
 		maps.Equal(h.headers, other.headers) &&
 		maps.Equal(h.queryParams, other.queryParams) &&
+		maps.EqualFunc(h.query, other.query, slices.Equal[[]string]) &&
 		maps.Equal(h.pathParams, other.pathParams)
 }
 
@@ -48,6 +91,47 @@ type HttpResponse struct {
 	headers    map[string]string
 }
 
+// NewHttpResponse creates an empty response. Middlewares can use it with Context.SetResponse to capture what the handlers after them write
+func NewHttpResponse() *HttpResponse {
+	return &HttpResponse{
+		headers: make(map[string]string),
+	}
+}
+
+// StatusCode returns the status code of the response. It is 200 until a handler sets one, since that is what gets sent
+func (resp *HttpResponse) StatusCode() int {
+	if resp.statusCode == 0 {
+		return http.StatusOK
+	}
+	return resp.statusCode
+}
+
+func (resp *HttpResponse) SetStatusCode(statusCode int) {
+	resp.statusCode = statusCode
+}
+
+// Headers returns the headers of the response. Changes to the map are sent with the response
+func (resp *HttpResponse) Headers() map[string]string {
+	if resp.headers == nil {
+		resp.headers = make(map[string]string)
+	}
+	return resp.headers
+}
+
+func (resp *HttpResponse) Header(key string) (string, bool) {
+	value, ok := resp.headers[key]
+	return value, ok
+}
+
+func (resp *HttpResponse) DeleteHeader(key string) {
+	delete(resp.headers, key)
+}
+
+// Body returns the body of the response
+func (resp *HttpResponse) Body() []byte {
+	return resp.body
+}
+
 func (resp *HttpResponse) SetHeader(key string, value string) {
 	if resp.headers == nil {
 		resp.headers = make(map[string]string)
@@ -55,6 +139,30 @@ func (resp *HttpResponse) SetHeader(key string, value string) {
 	resp.headers[key] = value
 }
 
+func (resp *HttpResponse) clone() *HttpResponse {
+	return &HttpResponse{
+		statusCode: resp.statusCode,
+		body:       slices.Clone(resp.body),
+		headers:    maps.Clone(resp.headers),
+	}
+}
+
+// AddHeaderValue appends value to a comma separated header like Vary, unless it is already present
+func (resp *HttpResponse) AddHeaderValue(key string, value string) {
+	existing, ok := resp.headers[key]
+	if !ok || existing == "" {
+		resp.SetHeader(key, value)
+		return
+	}
+
+	for part := range strings.SplitSeq(existing, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), value) {
+			return
+		}
+	}
+	resp.SetHeader(key, existing+", "+value)
+}
+
 func (resp *HttpResponse) Send(body []byte) {
 	resp.body = body
 }
@@ -0,0 +1,258 @@
+package whiskey
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator reports whether a field value satisfies a rule. param is the text after `=` in the rule (`min=1` has param "1") and empty for rules without one
+type Validator func(field reflect.Value, param string) bool
+
+// FieldError describes a single rule a field failed
+type FieldError struct {
+	Field string `json:"field"`           // Name of the struct field
+	Path  string `json:"path"`            // Location of the field in the request, using json names. Nested fields are dotted and slice elements indexed like items[0].name
+	Rule  string `json:"rule"`            // The violated rule
+	Param string `json:"param,omitempty"` // Parameter of the violated rule, if any
+}
+
+// ValidationErrors lists every rule that failed while validating a struct
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	failures := make([]string, len(v))
+	for idx, fieldErr := range v {
+		failures[idx] = fieldErr.Path + ": " + fieldErr.Rule
+		if fieldErr.Param != "" {
+			failures[idx] += "=" + fieldErr.Param
+		}
+	}
+	return "validation failed: " + strings.Join(failures, ", ")
+}
+
+// httpError converts the validation failures into a 422 HttpError whose body lists every failure
+func (v ValidationErrors) httpError() HttpError {
+	httpErr := newHTTPErrorWithFields(http.StatusUnprocessableEntity, "validation failed", Json{"fields": v})
+	httpErr.Err = v
+	return httpErr
+}
+
+func defaultValidators() map[string]Validator {
+	return map[string]Validator{
+		"required": validateRequired,
+		"min":      validateMin,
+		"max":      validateMax,
+		"len":      validateLen,
+		"email":    validateEmail,
+		"url":      validateURL,
+		"oneof":    validateOneOf,
+	}
+}
+
+// validateStruct checks every `validate` tag in v and its nested structs. Rules other than required are skipped for zero values, so optional fields only
+// get validated when they are sent. It returns an HttpError with 422 wrapping ValidationErrors if any rule fails
+func validateStruct(v any, validators map[string]Validator) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if err := validateFields(value, "", validators, &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs.httpError()
+	}
+	return nil
+}
+
+func validateFields(value reflect.Value, prefix string, validators map[string]Validator, errs *ValidationErrors) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		path := prefix + fieldName(field)
+		fieldValue := value.Field(i)
+
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := validateField(fieldValue, field.Name, path, tag, validators, errs); err != nil {
+				return err
+			}
+		}
+
+		if err := validateNested(fieldValue, path, validators, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNested walks into struct, pointer to struct and slice of struct fields
+func validateNested(value reflect.Value, path string, validators map[string]Validator, errs *ValidationErrors) error {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return validateNested(value.Elem(), path, validators, errs)
+	case reflect.Struct:
+		return validateFields(value, path+".", validators, errs)
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < value.Len(); idx++ {
+			if err := validateNested(value.Index(idx), fmt.Sprintf("%s[%d]", path, idx), validators, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateField(value reflect.Value, name string, path string, tag string, validators map[string]Validator, errs *ValidationErrors) error {
+	rules := strings.Split(tag, ",")
+	required := slices.Contains(rules, "required")
+
+	if value.IsZero() && !required {
+		return nil
+	}
+
+	for _, rule := range rules {
+		ruleName, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if ruleName == "" {
+			continue
+		}
+
+		validator, ok := validators[ruleName]
+		if !ok {
+			return fmt.Errorf("unknown validation rule %q on field %s", ruleName, name)
+		}
+
+		if !validator(value, param) {
+			*errs = append(*errs, FieldError{Field: name, Path: path, Rule: ruleName, Param: param})
+			if ruleName == "required" {
+				// The remaining rules can't pass on a missing value, reporting them would only add noise
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// fieldName returns the json name of a field, or the field name if it has no json tag
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func validateRequired(field reflect.Value, _ string) bool {
+	return !field.IsZero()
+}
+
+// indirect dereferences pointer fields so rules check the value they point to. It reports false for nil pointers
+func indirect(field reflect.Value) (reflect.Value, bool) {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return field, false
+		}
+		field = field.Elem()
+	}
+	return field, true
+}
+
+// compareSize compares the value of numbers or the length of strings, slices and maps against param
+func compareSize(field reflect.Value, param string, compare func(size float64, limit float64) bool) bool {
+	field, ok := indirect(field)
+	if !ok {
+		return false
+	}
+
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return compare(float64(utf8.RuneCountInString(field.String())), limit)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return compare(float64(field.Len()), limit)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compare(float64(field.Int()), limit)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compare(float64(field.Uint()), limit)
+	case reflect.Float32, reflect.Float64:
+		return compare(field.Float(), limit)
+	default:
+		return false
+	}
+}
+
+func validateMin(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(size float64, limit float64) bool { return size >= limit })
+}
+
+func validateMax(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(size float64, limit float64) bool { return size <= limit })
+}
+
+func validateLen(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(size float64, limit float64) bool { return size == limit })
+}
+
+func validateEmail(field reflect.Value, _ string) bool {
+	field, ok := indirect(field)
+	if !ok || field.Kind() != reflect.String {
+		return false
+	}
+	address, err := mail.ParseAddress(field.String())
+	// ParseAddress accepts display names like `John <john@example.com>`, we only want the bare address
+	return err == nil && address.Address == field.String()
+}
+
+func validateURL(field reflect.Value, _ string) bool {
+	field, ok := indirect(field)
+	if !ok || field.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.ParseRequestURI(field.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func validateOneOf(field reflect.Value, param string) bool {
+	field, ok := indirect(field)
+	if !ok {
+		return false
+	}
+	var value string
+	switch field.Kind() {
+	case reflect.String:
+		value = field.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = strconv.FormatInt(field.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = strconv.FormatUint(field.Uint(), 10)
+	default:
+		return false
+	}
+	return slices.Contains(strings.Fields(param), value)
+}
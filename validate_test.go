@@ -0,0 +1,165 @@
+package whiskey
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateStruct(t *testing.T) {
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+	type Item struct {
+		Name string `json:"name" validate:"required,max=5"`
+	}
+	type Request struct {
+		Name    string   `json:"name" validate:"required,min=1,max=8"`
+		Email   string   `json:"email" validate:"email"`
+		Role    string   `json:"role" validate:"oneof=admin user"`
+		Age     int      `json:"age" validate:"min=18"`
+		Website string   `json:"website" validate:"url"`
+		Tags    []string `json:"tags" validate:"max=2"`
+		Address Address  `json:"address"`
+		Items   []Item   `json:"items"`
+		Manager *Address `json:"manager"`
+	}
+
+	tests := []struct {
+		name    string
+		input   Request
+		wantErr ValidationErrors
+	}{
+		{
+			name: "Valid struct",
+			input: Request{
+				Name:    "john",
+				Email:   "john@example.com",
+				Role:    "admin",
+				Age:     30,
+				Website: "https://example.com",
+				Address: Address{City: "Paris"},
+			},
+		},
+		{
+			name:  "Optional fields are skipped when empty",
+			input: Request{Name: "john", Address: Address{City: "Paris"}},
+		},
+		{
+			name: "Every failure is reported",
+			input: Request{
+				Email:   "John <john@example.com>",
+				Role:    "guest",
+				Age:     12,
+				Website: "example.com",
+				Tags:    []string{"a", "b", "c"},
+				Items:   []Item{{Name: "ok"}, {Name: "too long"}},
+				Manager: &Address{},
+			},
+			wantErr: ValidationErrors{
+				{Field: "Name", Path: "name", Rule: "required"},
+				{Field: "Email", Path: "email", Rule: "email"},
+				{Field: "Role", Path: "role", Rule: "oneof", Param: "admin user"},
+				{Field: "Age", Path: "age", Rule: "min", Param: "18"},
+				{Field: "Website", Path: "website", Rule: "url"},
+				{Field: "Tags", Path: "tags", Rule: "max", Param: "2"},
+				{Field: "City", Path: "address.city", Rule: "required"},
+				{Field: "Name", Path: "items[1].name", Rule: "max", Param: "5"},
+				{Field: "City", Path: "manager.city", Rule: "required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStruct(&tt.input, defaultValidators())
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Expected no error but got %v", err)
+				}
+				return
+			}
+
+			var httpErr HttpError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnprocessableEntity {
+				t.Fatalf("Expected 422 HttpError but got %v", err)
+			}
+
+			var validationErrs ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				t.Fatalf("Expected ValidationErrors to be wrapped, got %v", err)
+			}
+			if !reflect.DeepEqual(validationErrs, tt.wantErr) {
+				t.Errorf("Expected %+v but got %+v", tt.wantErr, validationErrs)
+			}
+			if !strings.Contains(httpErr.Body, `"path":"items[1].name"`) {
+				t.Errorf("Expected body to list failing paths, got %s", httpErr.Body)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownRule(t *testing.T) {
+	type Request struct {
+		Name string `json:"name" validate:"slug"`
+	}
+
+	err := validateStruct(&Request{Name: "hello world"}, defaultValidators())
+	if err == nil || !strings.Contains(err.Error(), `unknown validation rule "slug"`) {
+		t.Fatalf("Expected unknown rule error but got %v", err)
+	}
+}
+
+func TestValidatePointerFields(t *testing.T) {
+	type Request struct {
+		Email   *string `json:"email" validate:"email"`
+		Website *string `json:"website" validate:"url"`
+		Plan    *string `json:"plan" validate:"oneof=free pro"`
+	}
+
+	email, website, plan := "bob@example.com", "https://example.com", "pro"
+	if err := validateStruct(&Request{Email: &email, Website: &website, Plan: &plan}, defaultValidators()); err != nil {
+		t.Fatalf("Expected valid pointer fields to pass but got %v", err)
+	}
+	if err := validateStruct(&Request{}, defaultValidators()); err != nil {
+		t.Fatalf("Expected nil optional fields to pass but got %v", err)
+	}
+
+	invalidEmail, invalidPlan := "bob", "enterprise"
+	err := validateStruct(&Request{Email: &invalidEmail, Plan: &invalidPlan}, defaultValidators())
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 2 {
+		t.Fatalf("Expected email and plan to fail but got %v", err)
+	}
+}
+
+func TestContextBindValidates(t *testing.T) {
+	type Query struct {
+		Slug string `json:"slug" validate:"required,slug"`
+	}
+
+	engine := New()
+	engine.RegisterValidator("slug", func(field reflect.Value, _ string) bool {
+		return !strings.ContainsAny(field.String(), " /")
+	})
+
+	ctx := RequestContext{
+		request: HttpRequest{query: parseQuery("slug=hello+world")},
+		engine:  &engine,
+	}
+
+	var query Query
+	err := ctx.BindQuery(&query)
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 1 || validationErrs[0].Rule != "slug" {
+		t.Fatalf("Expected slug validation failure but got %v", err)
+	}
+
+	ctx.request.query = parseQuery("slug=hello-world")
+	if err := ctx.BindQuery(&query); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+}
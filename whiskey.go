@@ -1,10 +1,14 @@
 package whiskey
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,10 +21,70 @@ var stringContentTypes = []string{
 }
 
 type Whiskey struct {
-	router       *router
-	config       ServerConfig
-	accessLogger *log.Logger
-	errorLogger  *log.Logger
+	router        *router
+	config        ServerConfig
+	accessLogger  *log.Logger
+	errorLogger   *log.Logger
+	decoders      map[string]BodyDecoder // Decoders used by BindBody, keyed by media type
+	encoders      []registeredEncoder    // Encoders Negotiate picks from, in order of preference
+	validators    map[string]Validator   // Rules available in `validate` struct tags
+	middlewares   []HttpHandler          // Run before the handlers of every route, in the order they were added
+	errorMappings []errorMapping         // Convert domain errors to HttpErrors before the global error handler sees them
+	hooks         hooks                  // Callbacks registered with the On* methods
+	state         *serverState
+}
+
+// serverState tracks the listener and in-flight connections so the server can be shut down
+type serverState struct {
+	mu       sync.Mutex
+	listener net.Listener
+	closing  bool // Set by Shutdown, connections accepted afterwards are closed right away
+	conns    sync.WaitGroup
+	ctx      context.Context    // Parent of every request context
+	cancel   context.CancelFunc // Cancels every in-flight request
+}
+
+func newServerState() *serverState {
+	state := &serverState{}
+	state.ctx, state.cancel = context.WithCancel(context.Background())
+	return state
+}
+
+// start prepares the state for a new Run, so a server that was shut down can be started again
+func (s *serverState) start(ln net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener = ln
+	s.closing = false
+	if s.ctx.Err() != nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// track registers an accepted connection unless the server is shutting down, so Shutdown never waits while connections are still added
+func (s *serverState) track() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	s.conns.Add(1)
+	return true
+}
+
+// close marks the server as shutting down and returns the listener to close along with the function canceling in-flight requests
+func (s *serverState) close() (net.Listener, context.CancelFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closing = true
+	return s.listener, s.cancel
+}
+
+// context returns the parent of the request contexts of the current Run
+func (s *serverState) context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ctx
 }
 
 // Default settings for the Whiskey engine.
@@ -30,11 +94,14 @@ var (
 )
 
 var defaultConfig = ServerConfig{
-	Port:           8080,
-	Addr:           "0.0.0.0",
-	MaxConcurrency: 1000,
-	ReadTimeout:    10 * time.Second,
-	WriteTimeout:   10 * time.Second,
+	Port:               8080,
+	Addr:               "0.0.0.0",
+	MaxConcurrency:     1000,
+	MaxRequestBodySize: 10 << 20, // 10 MB
+	MaxMultipartMemory: 8 << 20,  // 8 MB
+	MaxMultipartParts:  1000,
+	ReadTimeout:        10 * time.Second,
+	WriteTimeout:       10 * time.Second,
 }
 
 // New creates a new Whiskey engine instance with default settings.
@@ -44,6 +111,10 @@ func New() Whiskey {
 		config:       defaultConfig,
 		errorLogger:  log.New(log.Writer(), "ERROR: ", log.LstdFlags),
 		accessLogger: log.New(log.Writer(), "ACCESS: ", log.LstdFlags),
+		decoders:     defaultDecoders(),
+		encoders:     defaultEncoders(),
+		validators:   defaultValidators(),
+		state:        newServerState(),
 	}
 }
 
@@ -62,6 +133,36 @@ func (w *Whiskey) WithErrorLogger(logger *log.Logger) *Whiskey {
 	return w
 }
 
+// RegisterDecoder registers the decoder BindBody uses for requests with the given media type, replacing any existing one.
+// Use it to support formats like msgpack, CBOR or protobuf
+func (w *Whiskey) RegisterDecoder(mediaType string, decoder BodyDecoder) {
+	w.decoders[strings.ToLower(mediaType)] = decoder
+}
+
+// RegisterEncoder registers the encoder Negotiate uses to produce the given media type, replacing any existing one.
+// Newly registered media types are preferred last when the client accepts several types equally
+func (w *Whiskey) RegisterEncoder(mediaType string, encoder BodyEncoder) {
+	mediaType = strings.ToLower(mediaType)
+	for idx, registered := range w.encoders {
+		if registered.mediaType == mediaType {
+			w.encoders[idx].encoder = encoder
+			return
+		}
+	}
+	w.encoders = append(w.encoders, registeredEncoder{mediaType: mediaType, encoder: encoder})
+}
+
+// RegisterValidator makes a custom rule available in `validate` struct tags, replacing any rule with the same name.
+// Bound structs are validated automatically, e.g. `validate:"required,slug"` once a "slug" validator is registered
+func (w *Whiskey) RegisterValidator(name string, validator Validator) {
+	w.validators[name] = validator
+}
+
+// Use adds middlewares that run before the handlers of every route, including the GlobalRequestHandler and the built-in 404 response
+func (w *Whiskey) Use(middlewares ...HttpHandler) {
+	w.middlewares = append(w.middlewares, middlewares...)
+}
+
 // GET registers a handler for the given path with the HTTP GET method.
 func (w *Whiskey) GET(path string, handlers ...HttpHandler) {
 	w.router.addHandler(path, http.MethodGet, handlers)
@@ -87,6 +188,12 @@ func (w *Whiskey) PATCH(path string, handlers ...HttpHandler) {
 	w.router.addHandler(path, http.MethodPatch, handlers)
 }
 
+// OPTIONS registers a handler for the given path with the HTTP OPTIONS method.
+// Paths without one answer OPTIONS requests with 204 No Content and an Allow header listing their methods
+func (w *Whiskey) OPTIONS(path string, handlers ...HttpHandler) {
+	w.router.addHandler(path, http.MethodOptions, handlers)
+}
+
 func (w *Whiskey) GlobalErrorHandler(handler HttpErrorHandler) {
 	w.router.setErrorHandler(handler)
 }
@@ -103,7 +210,7 @@ func (w *Whiskey) ConfigRoutes(routes []Route) {
 	}
 }
 
-// Run starts the HTTP server and blocks until it is stopped
+// Run starts the HTTP server and blocks until it is stopped with Shutdown. It can be called again once Shutdown returns
 func (w *Whiskey) Run() {
 	// Start the HTTP server
 	ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", w.config.Addr, w.config.Port))
@@ -112,15 +219,62 @@ func (w *Whiskey) Run() {
 	}
 	defer ln.Close()
 
+	w.state.start(ln)
+
 	w.accessLogger.Printf("Starting server on %s:%d\n", w.config.Addr, w.config.Port)
+	runHooks(w, "OnStart", w.hooks.start, StartEvent{Addr: ln.Addr(), Time: time.Now()})
 
 	for {
 		// This blocks until a connection is accepted
 		conn, err := ln.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				w.accessLogger.Println("Server stopped accepting connections")
+				return
+			}
 			w.errorLogger.Fatal("Error accepting connection:", err)
 		}
 
-		go w.handleConnection(conn)
+		if !w.state.track() {
+			// Shutdown has begun, the listener is about to be closed
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer w.state.conns.Done()
+			w.handleConnection(conn)
+		}()
+	}
+}
+
+// Shutdown stops accepting new connections and waits for in-flight requests to finish.
+// If ctx is done first, the context of every remaining request is canceled and ctx's error is returned
+func (w *Whiskey) Shutdown(ctx context.Context) (err error) {
+	start := time.Now()
+	defer func() {
+		now := time.Now()
+		runHooks(w, "OnShutdown", w.hooks.shutdown, ShutdownEvent{Time: now, Duration: now.Sub(start), Err: err})
+	}()
+
+	ln, cancel := w.state.close()
+	if ln != nil {
+		if err := ln.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.state.conns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		cancel()
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
 	}
 }